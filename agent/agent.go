@@ -2,11 +2,17 @@ package agent
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/json"
+	"expvar"
 	"io"
+	"net"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/pkg/errors"
@@ -14,12 +20,47 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 	insecurePkg "google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/metadata"
 
 	"github.com/spacelift-io/vcs-agent/privatevcs"
 	"github.com/spacelift-io/vcs-agent/privatevcs/validation"
+	"github.com/spacelift-io/vcs-agent/privatevcs/validation/allowlist"
+	"github.com/spacelift-io/vcs-agent/transport"
 )
 
+const defaultHTTPResponseChunkSize = 1 << 20
+
+// drainCloseTimeout is how long a drained stream waits for the gateway to close
+// it before cancelling it.
+const drainCloseTimeout = 5 * time.Second
+
+// ErrPoolRotated is returned by Run when the pool config was rotated while the
+// stream was connected. The stream keeps draining in the background, and a
+// replacement should be started right away.
+var ErrPoolRotated = errors.New("the pool config was rotated")
+
+// capabilities are the protocol capabilities supported by this agent.
+var capabilities = []string{
+	privatevcs.CapabilityChunkedResponses,
+	privatevcs.CapabilityMultiValueHeaders,
+	privatevcs.CapabilityMultiplexing,
+	privatevcs.CapabilityRequestTimeouts,
+	privatevcs.CapabilityCancellation,
+	privatevcs.CapabilityErrorDetails,
+	privatevcs.CapabilityGoingAway,
+}
+
+var errDraining = &requestError{
+	code: privatevcs.ErrorCode_ERROR_CODE_SHUTTING_DOWN,
+	err:  errors.New("the agent is shutting down and doesn't accept new requests"),
+}
+
+var errSaturated = &requestError{
+	code: privatevcs.ErrorCode_ERROR_CODE_STREAM_SATURATED,
+	err:  errors.New("the stream already has as many requests waiting as it handles concurrently"),
+}
+
 // RequestDoer is an interface for an entity that can perform http request
 type RequestDoer interface {
 	Do(req *http.Request) (*http.Response, error)
@@ -27,26 +68,113 @@ type RequestDoer interface {
 
 // AgentConfig contains configuration parameters for creating a new Agent
 type AgentConfig struct {
-	PoolConfig                     *privatevcs.AgentPoolConfig
+	PoolConfig *privatevcs.AgentPoolConfig
+	// GatewayHosts overrides the gateway addresses from the pool config, in
+	// order of preference.
+	GatewayHosts []string
+	// Target is the name of the target served by the agent, labelling its
+	// metrics. Defaults to "default".
+	Target                         string
 	TargetBaseEndpoint             string
 	Vendor                         string
 	Validator                      validation.Strategy
+	ValidationMode                 string
+	Version                        string
 	Metadata                       map[string]string
 	HTTPClient                     RequestDoer
 	HTTPDisableResponseCompression bool
-	DialInsecure                   bool
+	// HTTPResponseChunkSize is the maximum size of a single response body
+	// message. Larger bodies are streamed to gateways which support it.
+	// Defaults to 1 MiB.
+	HTTPResponseChunkSize int
+	// HTTPTimeout is the timeout of requests for which the gateway doesn't
+	// request one. Defaults to 25 seconds.
+	HTTPTimeout time.Duration
+	// HTTPMaxTimeout caps the timeout of any request. Zero means no cap.
+	HTTPMaxTimeout time.Duration
+	// HTTPEndpointTimeouts overrides the timeout of requests to specific
+	// endpoints, keyed by allowlist endpoint name, e.g. "Get Repository Tarball".
+	HTTPEndpointTimeouts map[string]time.Duration
+	// AllowlistPatterns returns the user-defined allowlist patterns whose
+	// names HTTPEndpointTimeouts may use besides the built-in ones. It's called
+	// for every request, so that reloaded patterns are picked up.
+	AllowlistPatterns func() *allowlist.Patterns
+	// StreamConcurrency is the maximum number of requests handled concurrently
+	// on a single gateway stream. Defaults to 1.
+	StreamConcurrency int
+	// KeepaliveTime is the interval of gRPC keepalive pings sent to the
+	// gateway. Zero disables keepalives.
+	KeepaliveTime time.Duration
+	// KeepaliveTimeout is how long the agent waits for a keepalive ping to be
+	// acknowledged before closing the connection.
+	KeepaliveTimeout time.Duration
+	// PingTimeout is how long a stream may go without receiving a ping from
+	// the gateway before it's considered dead and torn down. Zero disables
+	// the check.
+	PingTimeout time.Duration
+	// RedirectMode controls how redirects returned by the target are handled.
+	// Defaults to RedirectModeFollow. HTTPClient must return redirects instead
+	// of following them, which the agent ensures for an *http.Client.
+	RedirectMode RedirectMode
+	// RedirectAllowedHosts are hosts besides the target which redirects may go
+	// to, e.g. object storage serving archives. Patterns starting with "*."
+	// match all subdomains of the domain.
+	RedirectAllowedHosts []string
+	// MaxRedirects is the number of redirects followed for a single request.
+	// Defaults to 10.
+	MaxRedirects int
+	// GatewayProxy is the URL of the proxy gateway connections are tunnelled
+	// through, either http:// using HTTP CONNECT or socks5://. It's separate
+	// from any proxy used by HTTPClient.
+	GatewayProxy string
+	// GatewayPins are SHA-256 hashes of subject public key infos, base64
+	// encoded and optionally prefixed with "sha256/". If set, one of the
+	// certificates of the gateway's chain must match one of them.
+	GatewayPins []string
+	// GatewayClientCertificate is presented to the gateway.
+	GatewayClientCertificate *transport.ClientCertificate
+	DialInsecure             bool
 }
 
-// Agent is an agent connected to a VCS Gateway. Can handle only one concurrent request.
+// Agent is an agent connected to a VCS Gateway. Each stream it runs can handle
+// up to StreamConcurrency concurrent requests.
 type Agent struct {
-	poolConfig                     *privatevcs.AgentPoolConfig
+	gatewayHosts                   []string
 	targetBaseEndpoint             string
 	vendor                         validation.Vendor
 	metadata                       map[string]string
 	validator                      validation.Strategy
+	validationMode                 string
+	version                        string
 	httpClient                     RequestDoer
 	httpDisableResponseCompression bool
+	httpResponseChunkSize          int
+	httpTimeout                    time.Duration
+	httpMaxTimeout                 time.Duration
+	httpEndpointTimeouts           map[string]time.Duration
+	allowlistPatterns              func() *allowlist.Patterns
+	streamConcurrency              int
+	keepalive                      *keepalive.ClientParameters
+	pingTimeout                    time.Duration
+	redirectMode                   RedirectMode
+	redirectAllowedHosts           []string
+	maxRedirects                   int
+	gatewayDialer                  contextDialer
+	gatewayTLS                     *tls.Config
+	gatewayPins                    [][]byte
 	dialInsecure                   bool
+
+	pool      atomic.Pointer[pool]
+	poolMutex sync.Mutex
+
+	draining  chan struct{}
+	drainOnce sync.Once
+
+	metrics *expvar.Map
+
+	// streams are the running streams, including those draining in the
+	// background after the pool config was rotated.
+	streams sync.WaitGroup
 }
 
 // New creates a new Agent.
@@ -59,33 +187,271 @@ func New(config *AgentConfig) (*Agent, error) {
 		return nil, errors.New("TargetBaseEndpoint must be supplied")
 	}
 
-	return &Agent{
+	if _, err := parseTargetBaseEndpoint(config.TargetBaseEndpoint); err != nil {
+		return nil, errors.Wrap(err, "invalid TargetBaseEndpoint")
+	}
+
+	chunkSize := config.HTTPResponseChunkSize
+	if chunkSize < 0 {
+		return nil, errors.New("HTTPResponseChunkSize must not be negative")
+	} else if chunkSize == 0 {
+		chunkSize = defaultHTTPResponseChunkSize
+	}
+
+	timeout := config.HTTPTimeout
+	if timeout < 0 {
+		return nil, errors.New("HTTPTimeout must not be negative")
+	} else if timeout == 0 {
+		timeout = defaultHTTPTimeout
+	}
+
+	concurrency := config.StreamConcurrency
+	if concurrency < 0 {
+		return nil, errors.New("StreamConcurrency must not be negative")
+	} else if concurrency == 0 {
+		concurrency = 1
+	}
+
+	redirectMode := config.RedirectMode
+	switch redirectMode {
+	case "":
+		redirectMode = RedirectModeFollow
+	case RedirectModeFollow, RedirectModeReturn:
+	default:
+		return nil, errors.Errorf("unknown RedirectMode %q", redirectMode)
+	}
+
+	redirectAllowedHosts := make([]string, 0, len(config.RedirectAllowedHosts))
+	for _, host := range config.RedirectAllowedHosts {
+		redirectAllowedHosts = append(redirectAllowedHosts, strings.ToLower(strings.TrimSpace(host)))
+	}
+
+	maxRedirects := config.MaxRedirects
+	if maxRedirects < 0 {
+		return nil, errors.New("MaxRedirects must not be negative")
+	} else if maxRedirects == 0 {
+		maxRedirects = defaultMaxRedirects
+	}
+
+	// Redirects are followed by the agent, which applies its own policy.
+	httpClient := config.HTTPClient
+	if client, ok := httpClient.(*http.Client); ok {
+		client = &http.Client{
+			Transport:     client.Transport,
+			CheckRedirect: noRedirects,
+			Jar:           client.Jar,
+			Timeout:       client.Timeout,
+		}
+		httpClient = client
+	}
+
+	var keepaliveParams *keepalive.ClientParameters
+	if config.KeepaliveTime > 0 {
+		keepaliveParams = &keepalive.ClientParameters{
+			Time:                config.KeepaliveTime,
+			Timeout:             config.KeepaliveTimeout,
+			PermitWithoutStream: true,
+		}
+	}
+
+	var gatewayDialer contextDialer
+	if config.GatewayProxy != "" {
+		var err error
+		if gatewayDialer, err = newProxyDialer(config.GatewayProxy); err != nil {
+			return nil, errors.Wrap(err, "invalid GatewayProxy")
+		}
+	}
+
+	gatewayTLS := &tls.Config{MinVersion: tls.VersionTLS12}
+	if config.GatewayClientCertificate != nil {
+		if err := config.GatewayClientCertificate.Configure(gatewayTLS); err != nil {
+			return nil, errors.Wrap(err, "invalid GatewayClientCertificate")
+		}
+	}
+
+	gatewayPins, err := parsePins(config.GatewayPins)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid GatewayPins")
+	}
+
+	if config.DialInsecure && (len(gatewayPins) > 0 || config.GatewayClientCertificate != nil) {
+		return nil, errors.New("GatewayPins and GatewayClientCertificate can't be used with DialInsecure")
+	}
+
+	a := &Agent{
 		metadata:                       config.Metadata,
-		poolConfig:                     config.PoolConfig,
+		gatewayHosts:                   config.GatewayHosts,
 		targetBaseEndpoint:             strings.TrimSuffix(config.TargetBaseEndpoint, "/"),
 		validator:                      config.Validator,
+		validationMode:                 config.ValidationMode,
+		version:                        config.Version,
 		vendor:                         validation.Vendor(config.Vendor),
-		httpClient:                     config.HTTPClient,
+		httpClient:                     httpClient,
 		httpDisableResponseCompression: config.HTTPDisableResponseCompression,
+		httpResponseChunkSize:          chunkSize,
+		httpTimeout:                    timeout,
+		httpMaxTimeout:                 config.HTTPMaxTimeout,
+		httpEndpointTimeouts:           config.HTTPEndpointTimeouts,
+		allowlistPatterns:              config.AllowlistPatterns,
+		streamConcurrency:              concurrency,
+		keepalive:                      keepaliveParams,
+		pingTimeout:                    config.PingTimeout,
+		redirectMode:                   redirectMode,
+		redirectAllowedHosts:           redirectAllowedHosts,
+		maxRedirects:                   maxRedirects,
+		gatewayDialer:                  gatewayDialer,
+		gatewayTLS:                     gatewayTLS,
+		gatewayPins:                    gatewayPins,
 		dialInsecure:                   config.DialInsecure,
-	}, nil
+		draining:                       make(chan struct{}),
+		metrics:                        metricsOf(config.Target),
+	}
+	a.pool.Store(a.newPool(config.PoolConfig, nil))
+
+	return a, nil
+}
+
+// Drain makes all running streams stop accepting new requests, notify the
+// gateway and return once their in-flight requests have been handled. Streams
+// started after Drain return immediately.
+func (a *Agent) Drain() {
+	a.drainOnce.Do(func() { close(a.draining) })
 }
 
-// Run runs the agent and handles any incoming requests.
-func (a *Agent) Run(ctx *spcontext.Context) (outErr error) {
+// ResolveGateways periodically resolves the addresses of all gateways until the
+// context is done. Gateways which can't be resolved are avoided by new streams.
+// When connecting through a proxy, addresses are resolved by the proxy instead.
+func (a *Agent) ResolveGateways(ctx *spcontext.Context, interval time.Duration) {
+	if a.gatewayDialer != nil {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for host, err := range a.pool.Load().gateways.resolve(ctx, net.DefaultResolver) {
+			ctx.With("gateway", host, "error", err.Error()).Warnf("Couldn't resolve gateway address.")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Wait waits for all streams to return, including those draining in the
+// background after the pool config was rotated.
+func (a *Agent) Wait() {
+	a.streams.Wait()
+}
+
+// Run runs a single stream and handles any incoming requests. If the pool
+// config is rotated, it returns ErrPoolRotated as soon as the stream starts
+// draining, leaving the stream to drain in the background.
+func (a *Agent) Run(ctx *spcontext.Context) error {
+	rotated := make(chan struct{})
+	done := make(chan error, 1)
+
+	a.streams.Add(1)
+	go func() {
+		defer a.streams.Done()
+
+		err := a.run(ctx, rotated)
+
+		select {
+		case <-rotated:
+			if err != nil {
+				ctx.With("error", err.Error()).Warnf("Error draining the stream after the pool config rotation.")
+			}
+		default:
+		}
+
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		select {
+		case <-rotated:
+			return ErrPoolRotated
+		default:
+			return err
+		}
+	case <-rotated:
+		return ErrPoolRotated
+	}
+}
+
+// run runs a single stream, closing rotated if it starts draining because the
+// pool config was rotated.
+func (a *Agent) run(ctx *spcontext.Context, rotated chan struct{}) (outErr error) {
+	select {
+	case <-a.draining:
+		return nil
+	default:
+	}
+
+	// A pin mismatch is recorded, since gRPC only reports handshake errors as
+	// an unavailable status.
+	var pinErr atomic.Pointer[error]
+
 	var opts []grpc.DialOption
 	if a.dialInsecure {
 		opts = append(opts, grpc.WithTransportCredentials(insecurePkg.NewCredentials()))
 	} else {
-		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})))
+		tlsConfig := a.gatewayTLS.Clone()
+		if len(a.gatewayPins) > 0 {
+			tlsConfig.VerifyConnection = func(state tls.ConnectionState) error {
+				err := verifyPins(state, a.gatewayPins)
+				if err != nil {
+					pinErr.Store(&err)
+				}
+				return err
+			}
+		}
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	}
+
+	if a.keepalive != nil {
+		opts = append(opts, grpc.WithKeepaliveParams(*a.keepalive))
 	}
 
-	var host string
-	if host = a.poolConfig.Host; host == "" {
+	pool := a.pool.Load()
+
+	gateway := pool.gateways.acquire()
+	if gateway == nil {
 		return NewMisconfigurationError(errors.New("The token seems to be invalid. Please download a valid token from the VCS Agent Pool configuration page."))
 	}
+	defer func() { pool.gateways.release(gateway, outErr != nil) }()
+
+	ctx = ctx.With("gateway", gateway.host, "pool_id", pool.config.PoolULID)
+
+	target := gateway.host
+
+	// The last tunnel refusal is kept, since gRPC only reports dial errors as
+	// an unavailable status.
+	var proxyErr atomic.Pointer[ProxyError]
+	if a.gatewayDialer != nil {
+		// The proxy resolves the gateway address, which may not be resolvable
+		// locally.
+		target = "passthrough:///" + gateway.host
+
+		opts = append(opts, grpc.WithContextDialer(func(ctx context.Context, address string) (net.Conn, error) {
+			conn, err := a.gatewayDialer(ctx, address)
+
+			var refused *ProxyError
+			if errors.As(err, &refused) {
+				proxyErr.Store(refused)
+			}
+
+			return conn, err
+		}))
+	}
 
-	client, err := grpc.NewClient(host, opts...)
+	client, err := grpc.NewClient(target, opts...)
 	if err != nil {
 		return errors.Wrap(err, "couldn't dial gateway")
 	}
@@ -105,36 +471,132 @@ func (a *Agent) Run(ctx *spcontext.Context) (outErr error) {
 	}
 
 	md := metadata.New(map[string]string{
-		privatevcs.MetadataFieldVcsAgentPoolULID: a.poolConfig.PoolULID,
-		privatevcs.MetadataFieldVCSAgentKey:      a.poolConfig.Key,
+		privatevcs.MetadataFieldVcsAgentPoolULID: pool.config.PoolULID,
+		privatevcs.MetadataFieldVCSAgentKey:      pool.config.Key,
 		privatevcs.MetadataFieldVCSAgentMetadata: string(metadataJSON),
+
+		privatevcs.MetadataFieldVCSAgentVersion:           a.version,
+		privatevcs.MetadataFieldVCSAgentCapabilities:      strings.Join(capabilities, ","),
+		privatevcs.MetadataFieldVCSAgentVendor:            string(a.vendor),
+		privatevcs.MetadataFieldVCSAgentValidationMode:    a.validationMode,
+		privatevcs.MetadataFieldVCSAgentStreamConcurrency: strconv.Itoa(a.streamConcurrency),
 	})
-	stream, err := cli.Connect(metadata.NewOutgoingContext(ctx, md))
+
+	streamCtx, cancel := spcontext.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := cli.Connect(metadata.NewOutgoingContext(streamCtx, md))
 	if err != nil {
+		if err := pinErr.Load(); err != nil {
+			return NewMisconfigurationError(*err)
+		}
+		if refused := proxyErr.Load(); refused != nil {
+			return errors.Wrap(refused, "couldn't connect to gateway")
+		}
 		return errors.Wrap(err, "couldn't connect to gateway")
 	}
 
 	ctx.Infof("Successfully connected to Spacelift!")
+	a.addMetric(metricStreamsConnected, 1)
+
+	conn := newConnection(stream, cancel, a.streamConcurrency)
+	defer conn.stop()
+
+	// The liveness timer is reset on every ping. If it fires, the connection
+	// is most likely half-open and the stream is torn down to reconnect.
+	var livenessExpired atomic.Bool
+	resetLiveness := func() {}
+	if a.pingTimeout > 0 {
+		liveness := time.AfterFunc(a.pingTimeout, func() {
+			ctx.With("ping_timeout", a.pingTimeout).Warnf("No ping received from the gateway, tearing down the stream.")
+			a.addMetric(metricStreamLivenessTimeouts, 1)
+			livenessExpired.Store(true)
+			cancel()
+		})
+		defer liveness.Stop()
+
+		resetLiveness = func() { liveness.Reset(a.pingTimeout) }
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+
+		select {
+		case <-a.draining:
+			ctx.Infof("Draining stream.")
+		case <-pool.rotated:
+			ctx.Infof("Pool config rotated, draining stream.")
+			close(rotated)
+		case <-streamCtx.Done():
+			return
+		}
+
+		conn.drain(drainCloseTimeout)
+	}()
+	defer func() {
+		cancel()
+		<-drained
+	}()
+
+	// Once draining, the sending side of the stream may already be closed, so
+	// failing to respond is expected and not worth reporting.
+	send := func(res *privatevcs.Response) error {
+		err := conn.send(res)
+		if err != nil && conn.isDraining() {
+			ctx.With("id", res.GetId(), "error", err.Error()).Debugf("Couldn't respond on the draining stream.")
+			return nil
+		}
+		return err
+	}
 
 	for {
 		msg, err := stream.Recv()
 		if err == io.EOF {
 			break
 		} else if err != nil {
+			sendErr := conn.close()
+			if sendErr != nil {
+				return errors.Wrap(sendErr, "couldn't send response to gateway")
+			}
+			if livenessExpired.Load() {
+				return errors.Errorf("no ping received from the gateway in %s", a.pingTimeout)
+			}
+			if conn.isDraining() && streamCtx.Err() != nil {
+				return nil
+			}
 			return errors.Wrap(err, "couldn't receive message from gateway")
 		}
 
-		var responseMsg *privatevcs.Response
 		switch req := msg.Request.(type) {
 		case *privatevcs.Request_HttpRequest:
-			responseMsg = a.handleRequest(ctx, msg.Id, req.HttpRequest)
+			if err := conn.dispatch(streamCtx, msg.Id, func(ctx *spcontext.Context, send func(*privatevcs.Response) error) error {
+				return a.handleRequest(ctx, msg.Id, req.HttpRequest, send)
+			}); err != nil {
+				if err := send(errorResponse(msg.Id, err)); err != nil {
+					_ = conn.close()
+					return errors.Wrap(err, "couldn't send response to gateway")
+				}
+			}
+		case *privatevcs.Request_CancelRequest:
+			if !conn.cancelRequest(req.CancelRequest.Id) {
+				ctx.With("id", req.CancelRequest.Id).Debugf("Request to cancel is not in flight.")
+			}
 		case *privatevcs.Request_PingRequest:
-			responseMsg = a.handlePing(msg.Id)
+			resetLiveness()
+			if err := send(a.handlePing(msg.Id)); err != nil {
+				_ = conn.close()
+				return errors.Wrap(err, "couldn't send response to gateway")
+			}
 		}
+	}
 
-		if err := stream.Send(responseMsg); err != nil {
-			return errors.Wrap(err, "couldn't send response to gateway")
-		}
+	if err := conn.wait(); err != nil {
+		return errors.Wrap(err, "couldn't send response to gateway")
+	}
+
+	if conn.isDraining() {
+		return nil
 	}
 
 	if err := stream.CloseSend(); err != nil {
@@ -144,59 +606,72 @@ func (a *Agent) Run(ctx *spcontext.Context) (outErr error) {
 	return nil
 }
 
-func (a *Agent) handleRequest(ctx *spcontext.Context, id string, msg *privatevcs.HTTPRequest) *privatevcs.Response {
-	req, err := http.NewRequest(msg.Method, a.targetBaseEndpoint+msg.Path, bytes.NewReader(msg.Body))
+// handleRequest performs the HTTP request and sends the response using send.
+// The returned error is only non-nil if the response couldn't be sent.
+func (a *Agent) handleRequest(ctx *spcontext.Context, id string, msg *privatevcs.HTTPRequest, send func(*privatevcs.Response) error) error {
+	target, err := a.targetURL(msg.Path)
 	if err != nil {
-		return &privatevcs.Response{
-			Id: id,
-			Content: &privatevcs.Response_Error{
-				Error: errors.Wrap(err, "couldn't create request").Error(),
-			},
-		}
+		return send(errorResponse(id, err))
+	}
+
+	req, err := http.NewRequest(msg.Method, target.String(), bytes.NewReader(msg.Body))
+	if err != nil {
+		return send(errorResponse(id, &requestError{
+			code: privatevcs.ErrorCode_ERROR_CODE_INVALID_REQUEST,
+			err:  errors.Wrap(err, "couldn't create request"),
+		}))
 	}
 
 	ctx = validation.RewriteGitHubTarballRequest(ctx, a.vendor, req).With(
 		"id", id,
-		"pool_id", a.poolConfig.PoolULID,
 		"method", req.Method,
 		"raw_path", req.URL.EscapedPath(),
 		"path", req.URL.Path,
 	)
 
-	for key, value := range msg.Headers {
-		req.Header.Set(key, value)
+	if err := a.checkTargetHost(target, req.URL, req.Host); err != nil {
+		ctx.With("error", err.Error()).Warnf("Request to a host other than the target rejected.")
+		return send(errorResponse(id, err))
 	}
 
+	setRequestHeaders(req, msg)
+
 	if a.httpDisableResponseCompression {
 		req.Header.Set("Accept-Encoding", "identity")
 	}
 
 	ctx, err = a.validator.Validate(ctx, a.vendor, req)
 	if err != nil {
-		return &privatevcs.Response{
-			Id: id,
-			Content: &privatevcs.Response_Error{
-				Error: err.Error(),
-			},
+		var validationErr *validation.Error
+		if !errors.As(err, &validationErr) {
+			err = &requestError{code: privatevcs.ErrorCode_ERROR_CODE_REQUEST_BLOCKED, err: err}
 		}
+		return send(errorResponse(id, err))
 	}
 
-	timeoutCtx, cancel := spcontext.WithTimeout(ctx, time.Second*25)
+	timeout := a.requestTimeout(req, msg)
+	ctx = ctx.With("timeout", timeout)
+
+	timeoutCtx, cancel := spcontext.WithTimeout(ctx, timeout)
 	defer cancel()
-	req = req.WithContext(timeoutCtx)
+	req = req.WithContext(transport.WithPinnedResolution(timeoutCtx))
 
 	start := time.Now()
-	res, err := a.httpClient.Do(req)
+	res, err := a.do(ctx, target, req, msg.Body)
 	if err != nil {
+		var egressErr *transport.EgressDeniedError
+		if errors.As(err, &egressErr) {
+			ctx.With(
+				"host", egressErr.Host,
+				"addresses", egressErr.Addresses,
+			).Warnf("Request to an address outside the allowed egress ranges rejected.")
+			return send(errorResponse(id, errors.Wrap(err, "couldn't do request")))
+		}
+
 		ctx.With(
 			"error", err.Error(),
 		).Errorf("Error serving request.")
-		return &privatevcs.Response{
-			Id: id,
-			Content: &privatevcs.Response_Error{
-				Error: errors.Wrap(err, "couldn't do request").Error(),
-			},
-		}
+		return send(errorResponse(id, errors.Wrap(err, "couldn't do request")))
 	}
 	defer res.Body.Close() //nolint:errcheck // error not actionable after response is read
 
@@ -205,33 +680,97 @@ func (a *Agent) handleRequest(ctx *spcontext.Context, id string, msg *privatevcs
 		"status_code", res.StatusCode,
 	).Infof("Request served.")
 
-	data, err := io.ReadAll(res.Body)
+	// Read one byte past the chunk size to find out whether the body fits in
+	// a single message without buffering all of it.
+	data, err := io.ReadAll(io.LimitReader(res.Body, int64(a.httpResponseChunkSize)+1))
 	if err != nil {
-		return &privatevcs.Response{
-			Id: id,
-			Content: &privatevcs.Response_Error{
-				Error: errors.Wrap(err, "couldn't read response body").Error(),
-			},
-		}
+		return send(errorResponse(id, bodyReadError(err)))
 	}
 
-	headers := make(map[string]string, len(res.Header))
-	for key, values := range res.Header {
-		if len(values) > 0 {
-			headers[key] = values[0]
+	if len(data) > a.httpResponseChunkSize {
+		if msg.AllowChunkedResponse {
+			return a.streamResponse(ctx, id, res, data, send)
+		}
+
+		rest, err := io.ReadAll(res.Body)
+		if err != nil {
+			return send(errorResponse(id, bodyReadError(err)))
 		}
+		data = append(data, rest...)
 	}
 
-	return &privatevcs.Response{
+	headers, multiValueHeaders := responseHeaders(res.Header)
+
+	return send(&privatevcs.Response{
 		Id: id,
 		Content: &privatevcs.Response_HttpResponse{
 			HttpResponse: &privatevcs.HTTPResponse{
-				Status:  int64(res.StatusCode),
-				Headers: headers,
-				Body:    data,
+				Status:            int64(res.StatusCode),
+				Headers:           headers,
+				Body:              data,
+				MultiValueHeaders: multiValueHeaders,
 			},
 		},
+	})
+}
+
+// streamResponse sends the response as a head message, followed by body chunks
+// of at most httpResponseChunkSize bytes and a terminating end message. The
+// already read prefix of the body is sent before the rest of it.
+func (a *Agent) streamResponse(ctx *spcontext.Context, id string, res *http.Response, prefix []byte, send func(*privatevcs.Response) error) error {
+	headers, multiValueHeaders := responseHeaders(res.Header)
+
+	if err := send(&privatevcs.Response{
+		Id: id,
+		Content: &privatevcs.Response_HttpResponseHead{
+			HttpResponseHead: &privatevcs.HTTPResponseHead{
+				Status:            int64(res.StatusCode),
+				Headers:           headers,
+				MultiValueHeaders: multiValueHeaders,
+			},
+		},
+	}); err != nil {
+		return err
 	}
+
+	body := io.MultiReader(bytes.NewReader(prefix), res.Body)
+
+	var chunks int
+	var readErr error
+
+	for readErr == nil {
+		buffer := make([]byte, a.httpResponseChunkSize)
+
+		var n int
+		if n, readErr = io.ReadFull(body, buffer); n == 0 {
+			continue
+		}
+
+		if err := send(&privatevcs.Response{
+			Id: id,
+			Content: &privatevcs.Response_HttpResponseBodyChunk{
+				HttpResponseBodyChunk: &privatevcs.HTTPResponseBodyChunk{Data: buffer[:n]},
+			},
+		}); err != nil {
+			return err
+		}
+		chunks++
+	}
+
+	end := new(privatevcs.HTTPResponseEnd)
+	if readErr != io.EOF && readErr != io.ErrUnexpectedEOF {
+		ctx.With("error", readErr.Error()).Errorf("Error streaming response body.")
+		err := bodyReadError(readErr)
+		end.Error = err.Error()
+		end.ErrorDetails = errorDetails(err)
+	}
+
+	ctx.With("chunks", chunks).Debugf("Response body streamed.")
+
+	return send(&privatevcs.Response{
+		Id:      id,
+		Content: &privatevcs.Response_HttpResponseEnd{HttpResponseEnd: end},
+	})
 }
 
 func (a *Agent) handlePing(id string) *privatevcs.Response {
@@ -242,3 +781,28 @@ func (a *Agent) handlePing(id string) *privatevcs.Response {
 		},
 	}
 }
+
+func errorResponse(id string, err error) *privatevcs.Response {
+	return &privatevcs.Response{
+		Id: id,
+		Content: &privatevcs.Response_Error{
+			Error: err.Error(),
+		},
+		ErrorDetails: errorDetails(err),
+	}
+}
+
+// bodyReadError wraps an error reading the response body. Timeouts and
+// cancellations are left for errorDetails to classify, as they're not caused by
+// the target.
+func bodyReadError(err error) error {
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return errors.Wrap(err, "couldn't read response body")
+	}
+
+	return &requestError{
+		code: privatevcs.ErrorCode_ERROR_CODE_BODY_READ_ERROR,
+		err:  errors.Wrap(err, "couldn't read response body"),
+	}
+}
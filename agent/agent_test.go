@@ -0,0 +1,127 @@
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/spacelift-io/spcontext"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacelift-io/vcs-agent/privatevcs"
+	"github.com/spacelift-io/vcs-agent/privatevcs/validation/blocklist"
+)
+
+func newTestAgent(t *testing.T, handler http.HandlerFunc) *Agent {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	a, err := New(&AgentConfig{
+		PoolConfig:            &privatevcs.AgentPoolConfig{Host: "gateway:1983"},
+		TargetBaseEndpoint:    server.URL,
+		Vendor:                "gitlab",
+		Validator:             new(blocklist.List),
+		HTTPClient:            server.Client(),
+		HTTPResponseChunkSize: 4,
+	})
+	require.NoError(t, err, "could not create agent")
+
+	return a
+}
+
+func collectResponses(t *testing.T, a *Agent, msg *privatevcs.HTTPRequest) []*privatevcs.Response {
+	var out []*privatevcs.Response
+
+	err := a.handleRequest(spcontext.New(log.NewNopLogger()), "request-id", msg, func(res *privatevcs.Response) error {
+		out = append(out, res)
+		return nil
+	})
+	require.NoError(t, err, "could not handle request")
+
+	return out
+}
+
+func TestHandleRequestResponseChunking(t *testing.T) {
+	t.Run("with a body fitting in a single chunk", func(t *testing.T) {
+		a := newTestAgent(t, func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("abcd"))
+		})
+
+		responses := collectResponses(t, a, &privatevcs.HTTPRequest{Method: http.MethodGet, Path: "/", AllowChunkedResponse: true})
+
+		require.Len(t, responses, 1)
+		require.Equal(t, "abcd", string(responses[0].GetHttpResponse().GetBody()))
+	})
+
+	t.Run("with a large body", func(t *testing.T) {
+		a := newTestAgent(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Test", "yes")
+			w.WriteHeader(http.StatusTeapot)
+			_, _ = w.Write([]byte("abcdefghij"))
+		})
+
+		t.Run("when the gateway allows chunked responses", func(t *testing.T) {
+			responses := collectResponses(t, a, &privatevcs.HTTPRequest{Method: http.MethodGet, Path: "/", AllowChunkedResponse: true})
+
+			require.Len(t, responses, 5)
+
+			head := responses[0].GetHttpResponseHead()
+			require.NotNil(t, head, "first message should be the head")
+			require.EqualValues(t, http.StatusTeapot, head.GetStatus())
+			require.Equal(t, "yes", head.GetHeaders()["X-Test"])
+
+			var body []string
+			for _, response := range responses[1:4] {
+				require.Equal(t, "request-id", response.GetId())
+				body = append(body, string(response.GetHttpResponseBodyChunk().GetData()))
+			}
+			require.Equal(t, []string{"abcd", "efgh", "ij"}, body)
+
+			end := responses[4].GetHttpResponseEnd()
+			require.NotNil(t, end, "last message should be the end")
+			require.Empty(t, end.GetError())
+		})
+
+		t.Run("when the gateway doesn't allow chunked responses", func(t *testing.T) {
+			responses := collectResponses(t, a, &privatevcs.HTTPRequest{Method: http.MethodGet, Path: "/"})
+
+			require.Len(t, responses, 1)
+			require.Equal(t, "abcdefghij", string(responses[0].GetHttpResponse().GetBody()))
+		})
+	})
+}
+
+func TestHandleRequestMultiValueHeaders(t *testing.T) {
+	var received http.Header
+
+	a := newTestAgent(t, func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+
+		w.Header().Add("Link", `<https://example.com/?page=2>; rel="next"`)
+		w.Header().Add("Link", `<https://example.com/?page=5>; rel="last"`)
+	})
+
+	responses := collectResponses(t, a, &privatevcs.HTTPRequest{
+		Method: http.MethodGet,
+		Path:   "/",
+		Headers: map[string]string{
+			"Accept": "text/plain",
+			"Vary":   "Origin",
+		},
+		MultiValueHeaders: map[string]*privatevcs.HeaderValues{
+			"Vary": {Values: []string{"Origin", "Accept-Encoding"}},
+		},
+	})
+
+	require.Equal(t, []string{"text/plain"}, received.Values("Accept"))
+	require.Equal(t, []string{"Origin", "Accept-Encoding"}, received.Values("Vary"))
+
+	require.Len(t, responses, 1)
+	response := responses[0].GetHttpResponse()
+	require.Equal(t, `<https://example.com/?page=2>; rel="next"`, response.GetHeaders()["Link"])
+	require.Equal(t, []string{
+		`<https://example.com/?page=2>; rel="next"`,
+		`<https://example.com/?page=5>; rel="last"`,
+	}, response.GetMultiValueHeaders()["Link"].GetValues())
+}
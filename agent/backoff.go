@@ -0,0 +1,81 @@
+package agent
+
+import (
+	"math/rand/v2"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Backoff is an exponential reconnect policy with jitter. It is not safe for
+// concurrent use, each stream should use its own copy.
+type Backoff struct {
+	// Initial is the delay after the first failure.
+	Initial time.Duration
+
+	// Max caps the delay. Authentication failures always wait this long.
+	Max time.Duration
+
+	// Multiplier is the factor the delay grows by after each failure.
+	Multiplier float64
+
+	// Jitter is the fraction of the delay which is randomized, between 0 (no
+	// jitter) and 1 (full jitter).
+	Jitter float64
+
+	// ResetAfter is how long a stream must have been running for its end to
+	// reset the backoff.
+	ResetAfter time.Duration
+
+	failures int
+}
+
+// Next returns how long to wait before reconnecting after a stream which was
+// running for uptime ended with err.
+func (b *Backoff) Next(uptime time.Duration, err error) time.Duration {
+	if uptime >= b.ResetAfter {
+		b.failures = 0
+
+		// A healthy stream closed cleanly by the gateway is replaced immediately.
+		if err == nil {
+			return 0
+		}
+	}
+
+	if IsAuthError(err) {
+		b.failures++
+		return b.jitter(b.Max)
+	}
+
+	delay := float64(b.Initial)
+	for i := 0; i < b.failures && delay < float64(b.Max); i++ {
+		delay *= b.Multiplier
+	}
+	b.failures++
+
+	return b.jitter(min(time.Duration(delay), b.Max))
+}
+
+func (b *Backoff) jitter(delay time.Duration) time.Duration {
+	if b.Jitter <= 0 || delay <= 0 {
+		return delay
+	}
+
+	return delay - time.Duration(rand.Float64()*b.Jitter*float64(delay))
+}
+
+// IsAuthError returns whether the error is caused by the gateway rejecting the
+// agent's credentials.
+func IsAuthError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	switch status.Code(err) {
+	case codes.Unauthenticated, codes.PermissionDenied:
+		return true
+	default:
+		return false
+	}
+}
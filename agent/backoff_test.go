@@ -0,0 +1,78 @@
+package agent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestBackoffNext(t *testing.T) {
+	newBackoff := func() *Backoff {
+		return &Backoff{
+			Initial:    time.Second,
+			Max:        10 * time.Second,
+			Multiplier: 2,
+			ResetAfter: time.Minute,
+		}
+	}
+
+	transient := errors.New("connection refused")
+
+	t.Run("with consecutive failures", func(t *testing.T) {
+		sut := newBackoff()
+
+		var delays []time.Duration
+		for range 6 {
+			delays = append(delays, sut.Next(time.Second, transient))
+		}
+
+		require.Equal(t, []time.Duration{
+			time.Second,
+			2 * time.Second,
+			4 * time.Second,
+			8 * time.Second,
+			10 * time.Second,
+			10 * time.Second,
+		}, delays)
+	})
+
+	t.Run("with a healthy stream", func(t *testing.T) {
+		sut := newBackoff()
+		for range 4 {
+			sut.Next(time.Second, transient)
+		}
+
+		t.Run("closed by the gateway", func(t *testing.T) {
+			require.Zero(t, sut.Next(time.Hour, nil))
+			require.Equal(t, time.Second, sut.Next(time.Second, nil))
+		})
+
+		t.Run("failing", func(t *testing.T) {
+			require.Equal(t, time.Second, sut.Next(time.Hour, transient))
+		})
+	})
+
+	t.Run("with an authentication failure", func(t *testing.T) {
+		sut := newBackoff()
+
+		err := errors.Wrap(status.Error(codes.Unauthenticated, "invalid key"), "couldn't connect to gateway")
+
+		require.Equal(t, 10*time.Second, sut.Next(time.Hour, err))
+	})
+
+	t.Run("with jitter", func(t *testing.T) {
+		sut := newBackoff()
+		sut.Jitter = 0.5
+
+		for range 100 {
+			delay := sut.Next(time.Hour, transient)
+
+			require.GreaterOrEqual(t, delay, 500*time.Millisecond)
+			require.LessOrEqual(t, delay, time.Second)
+		}
+	})
+}
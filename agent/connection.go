@@ -0,0 +1,272 @@
+package agent
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/spacelift-io/spcontext"
+
+	"github.com/spacelift-io/vcs-agent/privatevcs"
+)
+
+// connection multiplexes requests received on a single gateway stream. Requests
+// are handled concurrently, up to the connection's concurrency limit, while
+// responses are correlated with requests by their ID. Requests waiting for a
+// concurrency slot are queued in the order they were received. Requests
+// received while the queue is full are rejected, so that receiving never
+// blocks and pings and cancellations are always handled.
+type connection struct {
+	stream privatevcs.Gateway_ConnectClient
+	cancel spcontext.CancelFunc
+
+	// gRPC streams don't support concurrent sends.
+	sendMutex sync.Mutex
+
+	// Guards draining, so that no request is dispatched once draining began,
+	// and the in-flight request registry.
+	mutex    sync.Mutex
+	draining bool
+	requests map[string]*inFlightRequest
+
+	queue    chan *queuedRequest
+	slots    chan struct{}
+	inFlight sync.WaitGroup
+
+	errOnce sync.Once
+	err     error
+}
+
+func newConnection(stream privatevcs.Gateway_ConnectClient, cancel spcontext.CancelFunc, concurrency int) *connection {
+	c := &connection{
+		stream:   stream,
+		cancel:   cancel,
+		queue:    make(chan *queuedRequest, concurrency),
+		slots:    make(chan struct{}, concurrency),
+		requests: make(map[string]*inFlightRequest),
+	}
+
+	go c.schedule()
+
+	return c
+}
+
+// inFlightRequest is a request dispatched on a connection which hasn't been
+// fully handled yet.
+type inFlightRequest struct {
+	cancel spcontext.CancelFunc
+
+	// Once cancelled by the gateway, no more responses are sent for the
+	// request except for the final cancellation notice.
+	cancelled atomic.Bool
+}
+
+// queuedRequest is a dispatched request waiting for a concurrency slot.
+type queuedRequest struct {
+	ctx     *spcontext.Context
+	id      string
+	request *inFlightRequest
+	handler func(*spcontext.Context, func(*privatevcs.Response) error) error
+}
+
+// send sends a single response message to the gateway.
+func (c *connection) send(res *privatevcs.Response) error {
+	c.sendMutex.Lock()
+	defer c.sendMutex.Unlock()
+
+	return c.stream.Send(res)
+}
+
+// dispatch queues the handler to be run in the background as soon as a
+// concurrency slot is available. The handler must use the send function it's
+// given to respond. A handler error breaks the whole connection. It returns
+// errDraining if the connection is draining and errSaturated if the queue is
+// full, in which case the handler won't be run.
+func (c *connection) dispatch(ctx *spcontext.Context, id string, handler func(*spcontext.Context, func(*privatevcs.Response) error) error) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.draining {
+		return errDraining
+	}
+
+	ctx, cancel := spcontext.WithCancel(ctx)
+	request := &inFlightRequest{cancel: cancel}
+
+	// A request ID reused by the gateway while still in flight takes over
+	// cancellation, the previous request can only be cancelled with the stream.
+	previous, reused := c.requests[id]
+	c.requests[id] = request
+	c.inFlight.Add(1)
+
+	select {
+	case c.queue <- &queuedRequest{ctx: ctx, id: id, request: request, handler: handler}:
+		return nil
+	default:
+		if reused {
+			c.requests[id] = previous
+		} else {
+			delete(c.requests, id)
+		}
+		c.inFlight.Done()
+		cancel()
+
+		return errSaturated
+	}
+}
+
+// schedule starts the queued requests in order, each as soon as a concurrency
+// slot is available. It returns once the queue is closed by stop.
+func (c *connection) schedule() {
+	for queued := range c.queue {
+		select {
+		case c.slots <- struct{}{}:
+			go c.handle(queued)
+		case <-queued.ctx.Done():
+			c.sendCancelled(queued.ctx, queued.id, queued.request)
+			c.finish(queued)
+		}
+	}
+}
+
+// handle runs the handler of the request holding a concurrency slot.
+func (c *connection) handle(queued *queuedRequest) {
+	defer c.finish(queued)
+	defer func() { <-c.slots }()
+
+	err := queued.handler(queued.ctx, func(res *privatevcs.Response) error {
+		if queued.request.cancelled.Load() {
+			return nil
+		}
+		return c.send(res)
+	})
+	if err != nil {
+		c.fail(err)
+		return
+	}
+
+	c.sendCancelled(queued.ctx, queued.id, queued.request)
+}
+
+// finish releases the request once it's been handled.
+func (c *connection) finish(queued *queuedRequest) {
+	c.forget(queued.id, queued.request)
+	queued.request.cancel()
+	c.inFlight.Done()
+}
+
+// cancelRequest cancels the in-flight request with the given ID. It returns
+// false if there is no such request.
+func (c *connection) cancelRequest(id string) bool {
+	c.mutex.Lock()
+	request, ok := c.requests[id]
+	delete(c.requests, id)
+	c.mutex.Unlock()
+
+	if !ok {
+		return false
+	}
+
+	request.cancelled.Store(true)
+	request.cancel()
+
+	return true
+}
+
+// sendCancelled notifies the gateway if the request has been cancelled by it.
+func (c *connection) sendCancelled(ctx *spcontext.Context, id string, request *inFlightRequest) {
+	if !request.cancelled.Load() {
+		return
+	}
+
+	ctx.With("id", id).Infof("Request cancelled by the gateway.")
+
+	if err := c.send(&privatevcs.Response{
+		Id:      id,
+		Content: &privatevcs.Response_Cancelled{Cancelled: &privatevcs.Cancelled{}},
+	}); err != nil {
+		c.fail(err)
+	}
+}
+
+func (c *connection) forget(id string, request *inFlightRequest) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.requests[id] == request {
+		delete(c.requests, id)
+	}
+}
+
+// drain stops dispatching new requests, notifies the gateway and closes the
+// sending side of the stream once all in-flight requests have been handled.
+// If the gateway doesn't close the stream in response, it's cancelled after
+// closeTimeout.
+func (c *connection) drain(closeTimeout time.Duration) {
+	c.mutex.Lock()
+	c.draining = true
+	c.mutex.Unlock()
+
+	if err := c.send(&privatevcs.Response{
+		Content: &privatevcs.Response_GoingAway{GoingAway: &privatevcs.GoingAway{}},
+	}); err != nil {
+		c.fail(err)
+		return
+	}
+
+	if err := c.wait(); err != nil {
+		return
+	}
+
+	c.sendMutex.Lock()
+	err := c.stream.CloseSend()
+	c.sendMutex.Unlock()
+
+	if err != nil {
+		c.fail(err)
+		return
+	}
+
+	select {
+	case <-c.stream.Context().Done():
+	case <-time.After(closeTimeout):
+		c.cancel()
+	}
+}
+
+// isDraining returns whether the connection is draining.
+func (c *connection) isDraining() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.draining
+}
+
+// fail records the first error breaking the connection and cancels the stream.
+func (c *connection) fail(err error) {
+	c.errOnce.Do(func() {
+		c.err = err
+		c.cancel()
+	})
+}
+
+// wait waits for all in-flight requests to be handled. It returns the error
+// which broke the connection, if any.
+func (c *connection) wait() error {
+	c.inFlight.Wait()
+
+	return c.err
+}
+
+// stop stops scheduling requests. It must be called once no more requests are
+// dispatched.
+func (c *connection) stop() {
+	close(c.queue)
+}
+
+// close cancels all in-flight requests and waits for their handlers to return.
+func (c *connection) close() error {
+	c.cancel()
+
+	return c.wait()
+}
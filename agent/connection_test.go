@@ -0,0 +1,562 @@
+package agent
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/spacelift-io/spcontext"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/spacelift-io/vcs-agent/privatevcs"
+	"github.com/spacelift-io/vcs-agent/privatevcs/validation/blocklist"
+)
+
+type testGateway struct {
+	privatevcs.UnimplementedGatewayServer
+
+	connect func(privatevcs.Gateway_ConnectServer) error
+}
+
+func (g *testGateway) Connect(stream privatevcs.Gateway_ConnectServer) error {
+	return g.connect(stream)
+}
+
+// startTestGateway starts an insecure gateway on a random local port and
+// returns its address.
+func startTestGateway(t *testing.T, connect func(privatevcs.Gateway_ConnectServer) error) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err, "could not listen")
+
+	server := grpc.NewServer()
+	privatevcs.RegisterGatewayServer(server, &testGateway{connect: connect})
+
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	return listener.Addr().String()
+}
+
+func TestRunMultiplexesRequests(t *testing.T) {
+	// The slow request only completes once the gateway received the response
+	// to the fast one, which was sent after it.
+	fastReceived := make(chan struct{})
+
+	target := newTestAgent(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			select {
+			case <-fastReceived:
+			case <-time.After(5 * time.Second):
+			}
+		}
+		_, _ = w.Write([]byte(r.URL.Path))
+	})
+
+	var order []string
+
+	host := startTestGateway(t, func(stream privatevcs.Gateway_ConnectServer) error {
+		for _, path := range []string{"/slow", "/fast"} {
+			if err := stream.Send(&privatevcs.Request{
+				Id: path,
+				Request: &privatevcs.Request_HttpRequest{
+					HttpRequest: &privatevcs.HTTPRequest{Method: http.MethodGet, Path: path},
+				},
+			}); err != nil {
+				return err
+			}
+		}
+
+		for range 2 {
+			res, err := stream.Recv()
+			if err != nil {
+				return err
+			}
+			if order = append(order, res.GetId()); res.GetId() == "/fast" {
+				close(fastReceived)
+			}
+		}
+
+		return nil
+	})
+
+	a, err := New(&AgentConfig{
+		PoolConfig:         &privatevcs.AgentPoolConfig{Host: host},
+		TargetBaseEndpoint: target.targetBaseEndpoint,
+		Validator:          new(blocklist.List),
+		HTTPClient:         target.httpClient,
+		StreamConcurrency:  2,
+		DialInsecure:       true,
+	})
+	require.NoError(t, err, "could not create agent")
+
+	require.NoError(t, a.Run(spcontext.New(log.NewNopLogger())))
+	require.Equal(t, []string{"/fast", "/slow"}, order)
+}
+
+func TestRunQueuesRequestsInOrder(t *testing.T) {
+	paths := []string{"/0", "/1", "/2", "/3"}
+
+	started := make(chan string, len(paths))
+	release := make(map[string]chan struct{}, len(paths))
+	for _, path := range paths {
+		release[path] = make(chan struct{})
+	}
+
+	target := newTestAgent(t, func(w http.ResponseWriter, r *http.Request) {
+		started <- r.URL.Path
+		<-release[r.URL.Path]
+		_, _ = w.Write([]byte(r.URL.Path))
+	})
+
+	var order []string
+
+	host := startTestGateway(t, func(stream privatevcs.Gateway_ConnectServer) error {
+		send := func(path string) error {
+			return stream.Send(&privatevcs.Request{
+				Id: path,
+				Request: &privatevcs.Request_HttpRequest{
+					HttpRequest: &privatevcs.HTTPRequest{Method: http.MethodGet, Path: path},
+				},
+			})
+		}
+
+		// Both slots are taken before the other requests are queued.
+		for _, path := range paths[:2] {
+			if err := send(path); err != nil {
+				return err
+			}
+		}
+		order = append(order, <-started, <-started)
+
+		for _, path := range paths[2:] {
+			if err := send(path); err != nil {
+				return err
+			}
+		}
+
+		// Each released slot goes to the oldest queued request.
+		for _, path := range paths[:2] {
+			close(release[path])
+			order = append(order, <-started)
+		}
+
+		for _, path := range paths[2:] {
+			close(release[path])
+		}
+
+		for range paths {
+			if _, err := stream.Recv(); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	a, err := New(&AgentConfig{
+		PoolConfig:         &privatevcs.AgentPoolConfig{Host: host},
+		TargetBaseEndpoint: target.targetBaseEndpoint,
+		Validator:          new(blocklist.List),
+		HTTPClient:         target.httpClient,
+		StreamConcurrency:  2,
+		DialInsecure:       true,
+	})
+	require.NoError(t, err, "could not create agent")
+
+	require.NoError(t, a.Run(spcontext.New(log.NewNopLogger())))
+	require.ElementsMatch(t, paths[:2], order[:2])
+	require.Equal(t, paths[2:], order[2:])
+}
+
+func TestRunReceivesWhileSaturated(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+
+	target := newTestAgent(t, func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		_, _ = w.Write([]byte("done"))
+	})
+
+	var pongs int
+	var received []*privatevcs.Response
+
+	host := startTestGateway(t, func(stream privatevcs.Gateway_ConnectServer) error {
+		for _, id := range []string{"in-flight", "queued"} {
+			if err := stream.Send(&privatevcs.Request{
+				Id: id,
+				Request: &privatevcs.Request_HttpRequest{
+					HttpRequest: &privatevcs.HTTPRequest{Method: http.MethodGet, Path: "/"},
+				},
+			}); err != nil {
+				return err
+			}
+		}
+
+		<-started
+
+		// Pings keep the stream alive for longer than the ping timeout while
+		// all slots are taken.
+		for range 10 {
+			if err := stream.Send(&privatevcs.Request{
+				Id:      "ping",
+				Request: &privatevcs.Request_PingRequest{PingRequest: &privatevcs.PingRequest{}},
+			}); err != nil {
+				return err
+			}
+
+			res, err := stream.Recv()
+			if err != nil {
+				return err
+			}
+			if res.GetPingResponse() != nil {
+				pongs++
+			}
+
+			time.Sleep(20 * time.Millisecond)
+		}
+
+		if err := stream.Send(&privatevcs.Request{
+			Id: "cancel",
+			Request: &privatevcs.Request_CancelRequest{
+				CancelRequest: &privatevcs.CancelRequest{Id: "queued"},
+			},
+		}); err != nil {
+			return err
+		}
+
+		res, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		received = append(received, res)
+
+		close(release)
+
+		if res, err = stream.Recv(); err != nil {
+			return err
+		}
+		received = append(received, res)
+
+		return nil
+	})
+
+	a, err := New(&AgentConfig{
+		PoolConfig:         &privatevcs.AgentPoolConfig{Host: host},
+		TargetBaseEndpoint: target.targetBaseEndpoint,
+		Validator:          new(blocklist.List),
+		HTTPClient:         target.httpClient,
+		StreamConcurrency:  1,
+		PingTimeout:        100 * time.Millisecond,
+		DialInsecure:       true,
+	})
+	require.NoError(t, err, "could not create agent")
+
+	require.NoError(t, a.Run(spcontext.New(log.NewNopLogger())))
+
+	require.Equal(t, 10, pongs)
+	require.Len(t, received, 2)
+	require.Equal(t, "queued", received[0].GetId())
+	require.NotNil(t, received[0].GetCancelled())
+	require.Equal(t, "in-flight", received[1].GetId())
+	require.Equal(t, "done", string(received[1].GetHttpResponse().GetBody()))
+}
+
+func TestConnectionDispatchSaturated(t *testing.T) {
+	// Without a scheduler, the queue is never emptied.
+	c := &connection{
+		queue:    make(chan *queuedRequest, 1),
+		requests: make(map[string]*inFlightRequest),
+	}
+
+	handler := func(*spcontext.Context, func(*privatevcs.Response) error) error { return nil }
+	ctx := spcontext.New(log.NewNopLogger())
+
+	require.NoError(t, c.dispatch(ctx, "queued", handler))
+	require.Equal(t, errSaturated, c.dispatch(ctx, "rejected", handler))
+
+	require.Contains(t, c.requests, "queued")
+	require.NotContains(t, c.requests, "rejected", "rejected requests shouldn't be cancellable")
+}
+
+func TestRunDrain(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+
+	target := newTestAgent(t, func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		_, _ = w.Write([]byte("done"))
+	})
+
+	var a *Agent
+	var received []*privatevcs.Response
+
+	host := startTestGateway(t, func(stream privatevcs.Gateway_ConnectServer) error {
+		send := func(id string) error {
+			return stream.Send(&privatevcs.Request{
+				Id: id,
+				Request: &privatevcs.Request_HttpRequest{
+					HttpRequest: &privatevcs.HTTPRequest{Method: http.MethodGet, Path: "/"},
+				},
+			})
+		}
+
+		if err := send("in-flight"); err != nil {
+			return err
+		}
+
+		<-started
+		a.Drain()
+
+		for {
+			res, err := stream.Recv()
+			if err == io.EOF {
+				return nil
+			} else if err != nil {
+				return err
+			}
+
+			received = append(received, res)
+
+			if res.GetGoingAway() != nil {
+				if err := send("late"); err != nil {
+					return err
+				}
+			} else if res.GetId() == "late" {
+				close(release)
+			}
+		}
+	})
+
+	var err error
+	a, err = New(&AgentConfig{
+		PoolConfig:         &privatevcs.AgentPoolConfig{Host: host},
+		TargetBaseEndpoint: target.targetBaseEndpoint,
+		Validator:          new(blocklist.List),
+		HTTPClient:         target.httpClient,
+		DialInsecure:       true,
+	})
+	require.NoError(t, err, "could not create agent")
+
+	require.NoError(t, a.Run(spcontext.New(log.NewNopLogger())))
+
+	require.Len(t, received, 3)
+	require.NotNil(t, received[0].GetGoingAway())
+	require.Equal(t, "late", received[1].GetId())
+	require.Equal(t, errDraining.Error(), received[1].GetError())
+	require.Equal(t, "in-flight", received[2].GetId())
+	require.Equal(t, "done", string(received[2].GetHttpResponse().GetBody()))
+
+	require.NoError(t, a.Run(spcontext.New(log.NewNopLogger())), "streams started after draining should return immediately")
+}
+
+func TestRunDrainAfterCloseSend(t *testing.T) {
+	var a *Agent
+
+	host := startTestGateway(t, func(stream privatevcs.Gateway_ConnectServer) error {
+		a.Drain()
+
+		// The agent closes its sending side right away, as nothing is in
+		// flight, so the response to the late request can't be sent.
+		for {
+			if _, err := stream.Recv(); err == io.EOF {
+				break
+			} else if err != nil {
+				return err
+			}
+		}
+
+		return stream.Send(&privatevcs.Request{
+			Id: "late",
+			Request: &privatevcs.Request_HttpRequest{
+				HttpRequest: &privatevcs.HTTPRequest{Method: http.MethodGet, Path: "/"},
+			},
+		})
+	})
+
+	var err error
+	a, err = New(&AgentConfig{
+		PoolConfig:         &privatevcs.AgentPoolConfig{Host: host},
+		TargetBaseEndpoint: "https://gitlab.example.com",
+		Validator:          new(blocklist.List),
+		DialInsecure:       true,
+	})
+	require.NoError(t, err, "could not create agent")
+
+	require.NoError(t, a.run(spcontext.New(log.NewNopLogger()), make(chan struct{})), "failing to respond while draining should be expected")
+}
+
+func TestRunCancelRequest(t *testing.T) {
+	started := make(chan struct{})
+
+	target := newTestAgent(t, func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+	})
+
+	var received []*privatevcs.Response
+
+	host := startTestGateway(t, func(stream privatevcs.Gateway_ConnectServer) error {
+		if err := stream.Send(&privatevcs.Request{
+			Id: "slow",
+			Request: &privatevcs.Request_HttpRequest{
+				HttpRequest: &privatevcs.HTTPRequest{Method: http.MethodGet, Path: "/"},
+			},
+		}); err != nil {
+			return err
+		}
+
+		<-started
+
+		if err := stream.Send(&privatevcs.Request{
+			Id: "cancel",
+			Request: &privatevcs.Request_CancelRequest{
+				CancelRequest: &privatevcs.CancelRequest{Id: "slow"},
+			},
+		}); err != nil {
+			return err
+		}
+
+		res, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		received = append(received, res)
+
+		return nil
+	})
+
+	a, err := New(&AgentConfig{
+		PoolConfig:         &privatevcs.AgentPoolConfig{Host: host},
+		TargetBaseEndpoint: target.targetBaseEndpoint,
+		Validator:          new(blocklist.List),
+		HTTPClient:         target.httpClient,
+		DialInsecure:       true,
+	})
+	require.NoError(t, err, "could not create agent")
+
+	require.NoError(t, a.Run(spcontext.New(log.NewNopLogger())))
+
+	require.Len(t, received, 1)
+	require.Equal(t, "slow", received[0].GetId())
+	require.NotNil(t, received[0].GetCancelled())
+}
+
+func TestRunCancelQueuedRequest(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+
+	target := newTestAgent(t, func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})
+
+	var received []*privatevcs.Response
+
+	host := startTestGateway(t, func(stream privatevcs.Gateway_ConnectServer) error {
+		for _, id := range []string{"in-flight", "queued"} {
+			if err := stream.Send(&privatevcs.Request{
+				Id: id,
+				Request: &privatevcs.Request_HttpRequest{
+					HttpRequest: &privatevcs.HTTPRequest{Method: http.MethodGet, Path: "/"},
+				},
+			}); err != nil {
+				return err
+			}
+		}
+
+		<-started
+
+		if err := stream.Send(&privatevcs.Request{
+			Id: "cancel",
+			Request: &privatevcs.Request_CancelRequest{
+				CancelRequest: &privatevcs.CancelRequest{Id: "queued"},
+			},
+		}); err != nil {
+			return err
+		}
+
+		for range 2 {
+			res, err := stream.Recv()
+			if err != nil {
+				return err
+			}
+			if received = append(received, res); res.GetId() == "queued" {
+				close(release)
+			}
+		}
+
+		return nil
+	})
+
+	a, err := New(&AgentConfig{
+		PoolConfig:         &privatevcs.AgentPoolConfig{Host: host},
+		TargetBaseEndpoint: target.targetBaseEndpoint,
+		Validator:          new(blocklist.List),
+		HTTPClient:         target.httpClient,
+		StreamConcurrency:  1,
+		DialInsecure:       true,
+	})
+	require.NoError(t, err, "could not create agent")
+
+	require.NoError(t, a.Run(spcontext.New(log.NewNopLogger())))
+
+	require.Len(t, received, 2)
+	require.Equal(t, "queued", received[0].GetId())
+	require.NotNil(t, received[0].GetCancelled())
+	require.Equal(t, "in-flight", received[1].GetId())
+}
+
+func TestRunAdvertisesCapabilities(t *testing.T) {
+	var md metadata.MD
+
+	host := startTestGateway(t, func(stream privatevcs.Gateway_ConnectServer) error {
+		md, _ = metadata.FromIncomingContext(stream.Context())
+		return nil
+	})
+
+	a, err := New(&AgentConfig{
+		PoolConfig:         &privatevcs.AgentPoolConfig{Host: host, PoolULID: "pool"},
+		TargetBaseEndpoint: "https://gitlab.example.com",
+		Vendor:             "gitlab",
+		Validator:          new(blocklist.List),
+		ValidationMode:     "blocklist",
+		Version:            "v1.2.3",
+		StreamConcurrency:  4,
+		DialInsecure:       true,
+	})
+	require.NoError(t, err, "could not create agent")
+
+	require.NoError(t, a.Run(spcontext.New(log.NewNopLogger())))
+
+	require.Equal(t, []string{"pool"}, md.Get(privatevcs.MetadataFieldVcsAgentPoolULID))
+	require.Equal(t, []string{"v1.2.3"}, md.Get(privatevcs.MetadataFieldVCSAgentVersion))
+	require.Equal(t, []string{"gitlab"}, md.Get(privatevcs.MetadataFieldVCSAgentVendor))
+	require.Equal(t, []string{"blocklist"}, md.Get(privatevcs.MetadataFieldVCSAgentValidationMode))
+	require.Equal(t, []string{"4"}, md.Get(privatevcs.MetadataFieldVCSAgentStreamConcurrency))
+	require.Contains(t, strings.Split(md.Get(privatevcs.MetadataFieldVCSAgentCapabilities)[0], ","), privatevcs.CapabilityCancellation)
+}
+
+func TestRunLivenessTimeout(t *testing.T) {
+	host := startTestGateway(t, func(stream privatevcs.Gateway_ConnectServer) error {
+		<-stream.Context().Done()
+		return nil
+	})
+
+	a, err := New(&AgentConfig{
+		PoolConfig:         &privatevcs.AgentPoolConfig{Host: host},
+		TargetBaseEndpoint: "https://gitlab.example.com",
+		Validator:          new(blocklist.List),
+		PingTimeout:        50 * time.Millisecond,
+		DialInsecure:       true,
+	})
+	require.NoError(t, err, "could not create agent")
+
+	err = a.Run(spcontext.New(log.NewNopLogger()))
+
+	require.EqualError(t, err, "no ping received from the gateway in 50ms")
+}
@@ -1,5 +1,17 @@
 package agent
 
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+
+	"github.com/spacelift-io/vcs-agent/privatevcs"
+	"github.com/spacelift-io/vcs-agent/privatevcs/validation"
+	"github.com/spacelift-io/vcs-agent/transport"
+)
+
 type MisconfigurationError struct {
 	err error
 }
@@ -15,3 +27,104 @@ func (e *MisconfigurationError) Error() string {
 func (e *MisconfigurationError) Unwrap() error {
 	return e.err
 }
+
+// requestError is a request failure with a known error code.
+type requestError struct {
+	code privatevcs.ErrorCode
+	err  error
+}
+
+func (e *requestError) Error() string {
+	return e.err.Error()
+}
+
+func (e *requestError) Unwrap() error {
+	return e.err
+}
+
+// errorDetails describes the error for the gateway, classifying it based on the
+// errors it wraps.
+func errorDetails(err error) *privatevcs.Error {
+	details := &privatevcs.Error{
+		Code:    privatevcs.ErrorCode_ERROR_CODE_UNKNOWN,
+		Message: err.Error(),
+	}
+
+	var (
+		reqErr        *requestError
+		validationErr *validation.Error
+		egressErr     *transport.EgressDeniedError
+		dnsErr        *net.DNSError
+		netErr        net.Error
+		opErr         *net.OpError
+	)
+
+	switch {
+	case errors.As(err, &reqErr):
+		details.Code = reqErr.code
+		details.Cause = rootCause(reqErr).Error()
+	case errors.As(err, &validationErr):
+		details.Code = validationErr.Code
+		details.Rule = validationErr.Rule
+		details.Endpoint = validationErr.Endpoint
+		details.Project = validationErr.Project
+		details.Cause = rootCause(validationErr).Error()
+	case errors.As(err, &egressErr):
+		details.Code = privatevcs.ErrorCode_ERROR_CODE_EGRESS_DENIED
+		details.Cause = egressErr.Error()
+	case errors.As(err, &dnsErr):
+		details.Code = privatevcs.ErrorCode_ERROR_CODE_DNS_FAILURE
+		details.Cause = dnsErr.Error()
+	case tlsCause(err) != nil:
+		details.Code = privatevcs.ErrorCode_ERROR_CODE_TLS_ERROR
+		details.Cause = tlsCause(err).Error()
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		details.Code = privatevcs.ErrorCode_ERROR_CODE_TIMEOUT
+	case errors.As(err, &opErr):
+		details.Code = privatevcs.ErrorCode_ERROR_CODE_CONNECTION_FAILED
+		details.Cause = opErr.Error()
+	}
+
+	return details
+}
+
+// tlsCause returns the TLS handshake or certificate verification error wrapped
+// by err, if any.
+func tlsCause(err error) error {
+	var (
+		verificationErr *tls.CertificateVerificationError
+		recordErr       tls.RecordHeaderError
+		alertErr        tls.AlertError
+		authorityErr    x509.UnknownAuthorityError
+		hostnameErr     x509.HostnameError
+		invalidErr      x509.CertificateInvalidError
+	)
+
+	switch {
+	case errors.As(err, &verificationErr):
+		return verificationErr
+	case errors.As(err, &recordErr):
+		return recordErr
+	case errors.As(err, &alertErr):
+		return alertErr
+	case errors.As(err, &authorityErr):
+		return authorityErr
+	case errors.As(err, &hostnameErr):
+		return hostnameErr
+	case errors.As(err, &invalidErr):
+		return invalidErr
+	default:
+		return nil
+	}
+}
+
+// rootCause returns the innermost error wrapped by err.
+func rootCause(err error) error {
+	for {
+		wrapped := errors.Unwrap(err)
+		if wrapped == nil {
+			return err
+		}
+		err = wrapped
+	}
+}
@@ -0,0 +1,131 @@
+package agent
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacelift-io/vcs-agent/privatevcs"
+	"github.com/spacelift-io/vcs-agent/privatevcs/validation/blocklist"
+	"github.com/spacelift-io/vcs-agent/transport"
+)
+
+func TestHandleRequestErrorDetails(t *testing.T) {
+	t.Run("when blocked by a rule", func(t *testing.T) {
+		a := newTestAgent(t, func(w http.ResponseWriter, r *http.Request) {})
+
+		validator := &blocklist.List{Rules: []*blocklist.Rule{{Name: "No deletes", Method: http.MethodDelete, Path: ".*"}}}
+		require.NoError(t, validator.Compile(), "could not compile blocklist")
+		a.validator = validator
+
+		responses := collectResponses(t, a, &privatevcs.HTTPRequest{Method: http.MethodDelete, Path: "/"})
+
+		require.Len(t, responses, 1)
+		require.Equal(t, `request blocked by rule "No deletes"`, responses[0].GetError())
+
+		details := responses[0].GetErrorDetails()
+		require.Equal(t, privatevcs.ErrorCode_ERROR_CODE_BLOCKED_BY_RULE, details.GetCode())
+		require.Equal(t, "No deletes", details.GetRule())
+		require.Equal(t, responses[0].GetError(), details.GetMessage())
+	})
+
+	t.Run("when the request times out", func(t *testing.T) {
+		a := newTestAgent(t, func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+		})
+
+		responses := collectResponses(t, a, &privatevcs.HTTPRequest{Method: http.MethodGet, Path: "/", TimeoutMillis: 10})
+
+		require.Len(t, responses, 1)
+		require.Equal(t, privatevcs.ErrorCode_ERROR_CODE_TIMEOUT, responses[0].GetErrorDetails().GetCode())
+	})
+
+	t.Run("when reading the body times out", func(t *testing.T) {
+		a := newTestAgent(t, func(w http.ResponseWriter, r *http.Request) {
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		})
+
+		responses := collectResponses(t, a, &privatevcs.HTTPRequest{Method: http.MethodGet, Path: "/", TimeoutMillis: 10})
+
+		require.Len(t, responses, 1)
+		require.Equal(t, privatevcs.ErrorCode_ERROR_CODE_TIMEOUT, responses[0].GetErrorDetails().GetCode())
+	})
+
+	t.Run("when the certificate is not trusted", func(t *testing.T) {
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		t.Cleanup(server.Close)
+
+		a := newTestAgent(t, func(w http.ResponseWriter, r *http.Request) {})
+		a.targetBaseEndpoint = server.URL
+		a.httpClient = &http.Client{Timeout: time.Second}
+
+		responses := collectResponses(t, a, &privatevcs.HTTPRequest{Method: http.MethodGet, Path: "/"})
+
+		require.Len(t, responses, 1)
+		require.Equal(t, privatevcs.ErrorCode_ERROR_CODE_TLS_ERROR, responses[0].GetErrorDetails().GetCode())
+		require.NotEmpty(t, responses[0].GetErrorDetails().GetCause())
+	})
+
+	t.Run("when the connection is refused", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		server.Close()
+
+		a := newTestAgent(t, func(w http.ResponseWriter, r *http.Request) {})
+		a.targetBaseEndpoint = server.URL
+
+		responses := collectResponses(t, a, &privatevcs.HTTPRequest{Method: http.MethodGet, Path: "/"})
+
+		require.Len(t, responses, 1)
+		require.Equal(t, privatevcs.ErrorCode_ERROR_CODE_CONNECTION_FAILED, responses[0].GetErrorDetails().GetCode())
+	})
+
+	t.Run("when the target is outside the egress ranges", func(t *testing.T) {
+		reached := false
+		a := newTestAgent(t, func(w http.ResponseWriter, r *http.Request) { reached = true })
+
+		targetTransport, err := transport.New(&transport.Config{EgressAllowedRanges: []string{"10.0.0.0/8"}})
+		require.NoError(t, err, "could not create transport")
+		a.httpClient = &http.Client{Transport: targetTransport}
+
+		responses := collectResponses(t, a, &privatevcs.HTTPRequest{Method: http.MethodGet, Path: "/"})
+
+		require.Len(t, responses, 1)
+		require.Equal(t, privatevcs.ErrorCode_ERROR_CODE_EGRESS_DENIED, responses[0].GetErrorDetails().GetCode())
+		require.Equal(t, "127.0.0.1 resolves to 127.0.0.1, outside the allowed egress ranges", responses[0].GetErrorDetails().GetCause())
+		require.False(t, reached, "the target shouldn't be reached")
+	})
+}
+
+func TestErrorDetailsShuttingDown(t *testing.T) {
+	details := errorResponse("id", errDraining).GetErrorDetails()
+
+	require.Equal(t, privatevcs.ErrorCode_ERROR_CODE_SHUTTING_DOWN, details.GetCode())
+	require.Equal(t, errDraining.Error(), details.GetMessage())
+}
+
+func TestBodyReadError(t *testing.T) {
+	t.Run("when the body can't be read", func(t *testing.T) {
+		details := errorDetails(bodyReadError(io.ErrClosedPipe))
+
+		require.Equal(t, privatevcs.ErrorCode_ERROR_CODE_BODY_READ_ERROR, details.GetCode())
+		require.Equal(t, "couldn't read response body: io: read/write on closed pipe", details.GetMessage())
+	})
+
+	t.Run("when reading the body times out", func(t *testing.T) {
+		details := errorDetails(bodyReadError(context.DeadlineExceeded))
+
+		require.Equal(t, privatevcs.ErrorCode_ERROR_CODE_TIMEOUT, details.GetCode())
+	})
+
+	t.Run("when the request is cancelled", func(t *testing.T) {
+		details := errorDetails(bodyReadError(context.Canceled))
+
+		require.NotEqual(t, privatevcs.ErrorCode_ERROR_CODE_BODY_READ_ERROR, details.GetCode())
+	})
+}
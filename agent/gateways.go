@@ -0,0 +1,136 @@
+package agent
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	gatewayInitialCooldown = 5 * time.Second
+	gatewayMaxCooldown     = 2 * time.Minute
+)
+
+// gateway is a single gateway address streams can connect to.
+type gateway struct {
+	host string
+
+	// All fields below are guarded by the pool's mutex.
+	streams        int
+	failures       int
+	unhealthyUntil time.Time
+	unresolvable   bool
+}
+
+func (g *gateway) healthy(now time.Time) bool {
+	return !g.unresolvable && !now.Before(g.unhealthyUntil)
+}
+
+// gatewayPool spreads streams across gateways, avoiding the ones which recently
+// failed or whose address can't be resolved.
+type gatewayPool struct {
+	mutex    sync.Mutex
+	gateways []*gateway
+	now      func() time.Time
+}
+
+func newGatewayPool(hosts []string) *gatewayPool {
+	pool := &gatewayPool{now: time.Now}
+
+	for _, host := range hosts {
+		pool.gateways = append(pool.gateways, &gateway{host: host})
+	}
+
+	return pool
+}
+
+// acquire picks the gateway for a new stream: the healthy one with the fewest
+// streams, preferring gateways listed first. If none is healthy, it picks the
+// one which will recover first. It returns nil if the pool is empty.
+func (p *gatewayPool) acquire() *gateway {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	now := p.now()
+
+	var best *gateway
+	for _, candidate := range p.gateways {
+		if best == nil || p.better(candidate, best, now) {
+			best = candidate
+		}
+	}
+
+	if best != nil {
+		best.streams++
+	}
+
+	return best
+}
+
+func (p *gatewayPool) better(candidate, current *gateway, now time.Time) bool {
+	candidateHealthy, currentHealthy := candidate.healthy(now), current.healthy(now)
+
+	switch {
+	case candidateHealthy != currentHealthy:
+		return candidateHealthy
+	case candidateHealthy:
+		return candidate.streams < current.streams
+	default:
+		return candidate.unhealthyUntil.Before(current.unhealthyUntil)
+	}
+}
+
+// release returns the gateway to the pool once the stream ended, recording
+// whether it failed. Consecutive failures put the gateway aside for
+// exponentially longer periods.
+func (p *gatewayPool) release(g *gateway, failed bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	g.streams--
+
+	if !failed {
+		g.failures = 0
+		g.unhealthyUntil = time.Time{}
+		return
+	}
+
+	cooldown := gatewayInitialCooldown
+	for i := 0; i < g.failures && cooldown < gatewayMaxCooldown; i++ {
+		cooldown *= 2
+	}
+	g.failures++
+	g.unhealthyUntil = p.now().Add(min(cooldown, gatewayMaxCooldown))
+}
+
+// resolve looks up the address of every gateway, marking the ones which can't
+// be resolved as unhealthy.
+func (p *gatewayPool) resolve(ctx context.Context, resolver *net.Resolver) map[string]error {
+	p.mutex.Lock()
+	gateways := append([]*gateway(nil), p.gateways...)
+	p.mutex.Unlock()
+
+	errs := make(map[string]error)
+
+	for _, g := range gateways {
+		hostname, _, err := net.SplitHostPort(g.host)
+		if err != nil {
+			hostname = g.host
+		}
+
+		if net.ParseIP(hostname) == nil {
+			_, err = resolver.LookupHost(ctx, hostname)
+		}
+
+		if err != nil {
+			errs[g.host] = err
+		}
+
+		p.mutex.Lock()
+		g.unresolvable = err != nil
+		p.mutex.Unlock()
+	}
+
+	return errs
+}
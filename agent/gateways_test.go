@@ -0,0 +1,80 @@
+package agent
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGatewayPool(t *testing.T) {
+	now := time.Now()
+
+	newPool := func() *gatewayPool {
+		pool := newGatewayPool([]string{"primary:1983", "secondary:1983"})
+		pool.now = func() time.Time { return now }
+		return pool
+	}
+
+	t.Run("spreads streams across gateways", func(t *testing.T) {
+		sut := newPool()
+
+		var hosts []string
+		for range 3 {
+			hosts = append(hosts, sut.acquire().host)
+		}
+
+		require.Equal(t, []string{"primary:1983", "secondary:1983", "primary:1983"}, hosts)
+	})
+
+	t.Run("fails over from a failing gateway", func(t *testing.T) {
+		sut := newPool()
+
+		sut.release(sut.acquire(), true)
+
+		require.Equal(t, "secondary:1983", sut.acquire().host)
+		require.Equal(t, "secondary:1983", sut.acquire().host)
+
+		t.Run("and goes back to it after the cooldown", func(t *testing.T) {
+			now = now.Add(gatewayInitialCooldown)
+
+			require.Equal(t, "primary:1983", sut.acquire().host)
+		})
+	})
+
+	t.Run("with all gateways failing picks the one recovering first", func(t *testing.T) {
+		sut := newPool()
+
+		primary, secondary := sut.acquire(), sut.acquire()
+		sut.release(primary, true)
+		sut.release(primary, true)
+		sut.release(secondary, true)
+
+		require.Equal(t, "secondary:1983", sut.acquire().host)
+	})
+
+	t.Run("with an empty pool", func(t *testing.T) {
+		require.Nil(t, newGatewayPool(nil).acquire())
+	})
+}
+
+func TestGatewayPoolResolve(t *testing.T) {
+	sut := newGatewayPool([]string{"gateway.example.com:1983", "127.0.0.1:1983"})
+
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(context.Context, string, string) (net.Conn, error) {
+			return nil, errors.New("no DNS server")
+		},
+	}
+
+	errs := sut.resolve(context.Background(), resolver)
+
+	require.Len(t, errs, 1)
+	require.Contains(t, errs, "gateway.example.com:1983")
+	require.Equal(t, "127.0.0.1:1983", sut.acquire().host)
+	require.Equal(t, "127.0.0.1:1983", sut.acquire().host, "unresolvable gateways should be avoided")
+}
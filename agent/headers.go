@@ -0,0 +1,40 @@
+package agent
+
+import (
+	"net/http"
+
+	"github.com/spacelift-io/vcs-agent/privatevcs"
+)
+
+// setRequestHeaders copies the headers sent by the gateway to the request.
+// Multi-value headers replace single-value ones with the same name, since
+// gateways aware of them send every header in both forms.
+func setRequestHeaders(req *http.Request, msg *privatevcs.HTTPRequest) {
+	for key, value := range msg.Headers {
+		req.Header.Set(key, value)
+	}
+
+	for key, values := range msg.MultiValueHeaders {
+		req.Header.Del(key)
+
+		for _, value := range values.GetValues() {
+			req.Header.Add(key, value)
+		}
+	}
+}
+
+// responseHeaders converts the response headers to both the legacy form, only
+// keeping the first value of each header, and the multi-value form.
+func responseHeaders(header http.Header) (map[string]string, map[string]*privatevcs.HeaderValues) {
+	headers := make(map[string]string, len(header))
+	multiValueHeaders := make(map[string]*privatevcs.HeaderValues, len(header))
+
+	for key, values := range header {
+		if len(values) > 0 {
+			headers[key] = values[0]
+		}
+		multiValueHeaders[key] = &privatevcs.HeaderValues{Values: values}
+	}
+
+	return headers, multiValueHeaders
+}
@@ -0,0 +1,54 @@
+package agent
+
+import (
+	"expvar"
+	"sync"
+)
+
+// metrics holds the agent's counters, exposed through expvar. The counters of
+// each target are also kept under "targets", keyed by the target name, so that
+// agents serving several targets can tell them apart.
+var metrics = expvar.NewMap("vcs_agent")
+
+var (
+	targetMetrics      = new(expvar.Map).Init()
+	targetMetricsMutex sync.Mutex
+)
+
+const (
+	metricStreamsConnected       = "streams_connected"
+	metricStreamLivenessTimeouts = "stream_liveness_timeouts"
+
+	// defaultTarget labels the metrics of agents without a target name.
+	defaultTarget = "default"
+)
+
+func init() {
+	metrics.Set("targets", targetMetrics)
+}
+
+// metricsOf returns the counters of the target, creating them if needed.
+func metricsOf(target string) *expvar.Map {
+	if target == "" {
+		target = defaultTarget
+	}
+
+	targetMetricsMutex.Lock()
+	defer targetMetricsMutex.Unlock()
+
+	if out, ok := targetMetrics.Get(target).(*expvar.Map); ok {
+		return out
+	}
+
+	out := new(expvar.Map).Init()
+	targetMetrics.Set(target, out)
+
+	return out
+}
+
+// addMetric adds delta to the counter, both in total and for the agent's
+// target.
+func (a *Agent) addMetric(name string, delta int64) {
+	metrics.Add(name, delta)
+	a.metrics.Add(name, delta)
+}
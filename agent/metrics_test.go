@@ -0,0 +1,47 @@
+package agent
+
+import (
+	"expvar"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacelift-io/vcs-agent/privatevcs"
+	"github.com/spacelift-io/vcs-agent/privatevcs/validation/blocklist"
+)
+
+func TestAddMetric(t *testing.T) {
+	newAgent := func(target string) *Agent {
+		a, err := New(&AgentConfig{
+			PoolConfig:         &privatevcs.AgentPoolConfig{Host: "gateway:1983"},
+			Target:             target,
+			TargetBaseEndpoint: "https://gitlab.example.com",
+			Validator:          new(blocklist.List),
+		})
+		require.NoError(t, err, "could not create agent")
+		return a
+	}
+
+	value := func(m *expvar.Map, name string) int64 {
+		counter, ok := m.Get(name).(*expvar.Int)
+		if !ok {
+			return 0
+		}
+		return counter.Value()
+	}
+
+	gitlab, bitbucket := newAgent("metrics-gitlab"), newAgent("metrics-bitbucket")
+
+	// The counters are global, so only their changes are checked.
+	total := value(metrics, metricStreamsConnected)
+	gitlabTotal := value(metricsOf("metrics-gitlab"), metricStreamsConnected)
+	bitbucketTotal := value(metricsOf("metrics-bitbucket"), metricStreamsConnected)
+
+	gitlab.addMetric(metricStreamsConnected, 2)
+	bitbucket.addMetric(metricStreamsConnected, 1)
+
+	require.Equal(t, total+3, value(metrics, metricStreamsConnected))
+	require.Equal(t, gitlabTotal+2, value(metricsOf("metrics-gitlab"), metricStreamsConnected))
+	require.Equal(t, bitbucketTotal+1, value(metricsOf("metrics-bitbucket"), metricStreamsConnected))
+	require.Same(t, metricsOf(""), metricsOf(defaultTarget))
+}
@@ -0,0 +1,70 @@
+package agent
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const pinPrefix = "sha256/"
+
+// parsePins parses SHA-256 hashes of subject public key infos, base64 encoded
+// and optionally prefixed with "sha256/".
+func parsePins(pins []string) ([][]byte, error) {
+	out := make([][]byte, 0, len(pins))
+
+	for _, pin := range pins {
+		hash, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(strings.TrimSpace(pin), pinPrefix))
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid pin %q", pin)
+		}
+
+		if len(hash) != sha256.Size {
+			return nil, errors.Errorf("invalid pin %q, expected a base64 encoded SHA-256 hash", pin)
+		}
+
+		out = append(out, hash)
+	}
+
+	return out, nil
+}
+
+// spkiPin returns the pin of the certificate's public key.
+func spkiPin(cert *x509.Certificate) []byte {
+	hash := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return hash[:]
+}
+
+// verifyPins checks that a certificate of any of the verified chains matches
+// one of the pins, so that pinning an intermediate or root CA works as well as
+// pinning the gateway's own key, including with cross-signed chains.
+func verifyPins(state tls.ConnectionState, pins [][]byte) error {
+	chains := state.VerifiedChains
+	if len(chains) == 0 {
+		chains = [][]*x509.Certificate{state.PeerCertificates}
+	}
+
+	var presented []string
+	for _, chain := range chains {
+		for _, cert := range chain {
+			pin := spkiPin(cert)
+
+			for _, expected := range pins {
+				if string(pin) == string(expected) {
+					return nil
+				}
+			}
+
+			if encoded := pinPrefix + base64.StdEncoding.EncodeToString(pin); !slices.Contains(presented, encoded) {
+				presented = append(presented, encoded)
+			}
+		}
+	}
+
+	return errors.Errorf("the gateway certificate chain doesn't match any of the configured pins, the connection may be intercepted (presented: %s)", strings.Join(presented, ", "))
+}
@@ -0,0 +1,113 @@
+package agent
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/pkg/errors"
+	"github.com/spacelift-io/spcontext"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/spacelift-io/vcs-agent/privatevcs"
+	"github.com/spacelift-io/vcs-agent/privatevcs/validation/blocklist"
+)
+
+func TestParsePins(t *testing.T) {
+	pin := base64.StdEncoding.EncodeToString(make([]byte, 32))
+
+	t.Run("with valid pins", func(t *testing.T) {
+		pins, err := parsePins([]string{pin, "sha256/" + pin})
+
+		require.NoError(t, err)
+		require.Len(t, pins, 2)
+	})
+
+	t.Run("with a pin of the wrong length", func(t *testing.T) {
+		_, err := parsePins([]string{"sha256/AAAA"})
+
+		require.EqualError(t, err, `invalid pin "sha256/AAAA", expected a base64 encoded SHA-256 hash`)
+	})
+}
+
+func TestVerifyPins(t *testing.T) {
+	leaf := &x509.Certificate{RawSubjectPublicKeyInfo: []byte("leaf")}
+	intermediate := &x509.Certificate{RawSubjectPublicKeyInfo: []byte("intermediate")}
+	crossSigned := &x509.Certificate{RawSubjectPublicKeyInfo: []byte("cross-signed")}
+
+	state := tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{leaf, intermediate},
+		VerifiedChains: [][]*x509.Certificate{
+			{leaf, intermediate},
+			{leaf, crossSigned},
+		},
+	}
+
+	t.Run("with a pin on another verified chain", func(t *testing.T) {
+		require.NoError(t, verifyPins(state, [][]byte{spkiPin(crossSigned)}))
+	})
+
+	t.Run("with no matching pin", func(t *testing.T) {
+		err := verifyPins(state, [][]byte{make([]byte, 32)})
+
+		require.ErrorContains(t, err, "doesn't match any of the configured pins")
+	})
+}
+
+func TestRunGatewayPinning(t *testing.T) {
+	// The test server's certificate is valid for 127.0.0.1.
+	tlsServer := httptest.NewUnstartedServer(http.NotFoundHandler())
+	tlsServer.StartTLS()
+	tlsServer.Close()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err, "could not listen")
+
+	server := grpc.NewServer(grpc.Creds(credentials.NewServerTLSFromCert(&tlsServer.TLS.Certificates[0])))
+	privatevcs.RegisterGatewayServer(server, &testGateway{connect: func(privatevcs.Gateway_ConnectServer) error {
+		return nil
+	}})
+
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(tlsServer.Certificate())
+
+	newAgent := func(pin []byte) *Agent {
+		a, err := New(&AgentConfig{
+			PoolConfig:         &privatevcs.AgentPoolConfig{Host: listener.Addr().String()},
+			TargetBaseEndpoint: "https://gitlab.example.com",
+			Validator:          new(blocklist.List),
+			GatewayPins:        []string{"sha256/" + base64.StdEncoding.EncodeToString(pin)},
+		})
+		require.NoError(t, err, "could not create agent")
+
+		a.gatewayTLS.RootCAs = roots
+
+		return a
+	}
+
+	t.Run("with a matching pin", func(t *testing.T) {
+		a := newAgent(spkiPin(tlsServer.Certificate()))
+
+		require.NoError(t, a.Run(spcontext.New(log.NewNopLogger())))
+	})
+
+	t.Run("with a mismatching pin", func(t *testing.T) {
+		a := newAgent(make([]byte, 32))
+
+		err := a.Run(spcontext.New(log.NewNopLogger()))
+
+		var misconfigurationErr *MisconfigurationError
+		require.True(t, errors.As(err, &misconfigurationErr), "expected a misconfiguration error, got %v", err)
+		require.ErrorContains(t, err, "doesn't match any of the configured pins")
+	})
+}
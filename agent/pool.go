@@ -0,0 +1,61 @@
+package agent
+
+import (
+	"slices"
+
+	"github.com/pkg/errors"
+
+	"github.com/spacelift-io/vcs-agent/privatevcs"
+)
+
+// pool is the agent pool the agent connects as, along with its gateways. It's
+// replaced when the pool config is rotated.
+type pool struct {
+	config   *privatevcs.AgentPoolConfig
+	hosts    []string
+	gateways *gatewayPool
+
+	// rotated is closed once the pool is replaced, making the streams
+	// connected with it drain.
+	rotated chan struct{}
+}
+
+func (a *Agent) newPool(config *privatevcs.AgentPoolConfig, previous *pool) *pool {
+	hosts := a.gatewayHosts
+	if len(hosts) == 0 {
+		hosts = config.GatewayHosts()
+	}
+
+	// The health of the gateways is kept if only the key changed.
+	gateways := newGatewayPool(hosts)
+	if previous != nil && slices.Equal(previous.hosts, hosts) {
+		gateways = previous.gateways
+	}
+
+	return &pool{
+		config:   config,
+		hosts:    hosts,
+		gateways: gateways,
+		rotated:  make(chan struct{}),
+	}
+}
+
+// SetPoolConfig replaces the pool config, for example after the pool token was
+// rotated. New streams connect using it, while streams connected using the
+// previous one drain in the background, their Run returning ErrPoolRotated.
+func (a *Agent) SetPoolConfig(config *privatevcs.AgentPoolConfig) error {
+	a.poolMutex.Lock()
+	defer a.poolMutex.Unlock()
+
+	previous := a.pool.Load()
+
+	next := a.newPool(config, previous)
+	if len(next.hosts) == 0 {
+		return errors.New("the pool config contains no gateway host")
+	}
+
+	a.pool.Store(next)
+	close(previous.rotated)
+
+	return nil
+}
@@ -0,0 +1,132 @@
+package agent
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/spacelift-io/spcontext"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/spacelift-io/vcs-agent/privatevcs"
+	"github.com/spacelift-io/vcs-agent/privatevcs/validation/blocklist"
+)
+
+func TestRunSetPoolConfig(t *testing.T) {
+	var a *Agent
+	var keys []string
+	goingAway := make(chan bool, 1)
+
+	host := startTestGateway(t, func(stream privatevcs.Gateway_ConnectServer) error {
+		md, _ := metadata.FromIncomingContext(stream.Context())
+		keys = append(keys, md.Get(privatevcs.MetadataFieldVCSAgentKey)...)
+
+		if len(keys) > 1 {
+			return nil
+		}
+
+		if err := a.SetPoolConfig(&privatevcs.AgentPoolConfig{Host: a.pool.Load().hosts[0], Key: "rotated"}); err != nil {
+			return err
+		}
+
+		res, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		goingAway <- res.GetGoingAway() != nil
+
+		return nil
+	})
+
+	var err error
+	a, err = New(&AgentConfig{
+		PoolConfig:         &privatevcs.AgentPoolConfig{Host: host, Key: "original"},
+		TargetBaseEndpoint: "https://gitlab.example.com",
+		Validator:          new(blocklist.List),
+		DialInsecure:       true,
+	})
+	require.NoError(t, err, "could not create agent")
+
+	require.ErrorIs(t, a.Run(spcontext.New(log.NewNopLogger())), ErrPoolRotated, "the stream should return as soon as it starts draining")
+
+	require.NoError(t, a.Run(spcontext.New(log.NewNopLogger())))
+	require.Equal(t, []string{"original", "rotated"}, keys)
+
+	a.Wait()
+	require.True(t, <-goingAway, "the stream should be drained after the rotation")
+
+	t.Run("without a gateway host", func(t *testing.T) {
+		err := a.SetPoolConfig(&privatevcs.AgentPoolConfig{Key: "invalid"})
+
+		require.EqualError(t, err, "the pool config contains no gateway host")
+		require.Equal(t, "rotated", a.pool.Load().config.Key)
+	})
+}
+
+func TestRunSetPoolConfigDrainsInBackground(t *testing.T) {
+	started, replaced := make(chan struct{}), make(chan struct{})
+
+	target := newTestAgent(t, func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-replaced
+		_, _ = w.Write([]byte("done"))
+	})
+
+	var a *Agent
+	var connections atomic.Int32
+	received := make(chan *privatevcs.Response, 2)
+
+	host := startTestGateway(t, func(stream privatevcs.Gateway_ConnectServer) error {
+		if connections.Add(1) > 1 {
+			close(replaced)
+			return nil
+		}
+
+		if err := stream.Send(&privatevcs.Request{
+			Id: "in-flight",
+			Request: &privatevcs.Request_HttpRequest{
+				HttpRequest: &privatevcs.HTTPRequest{Method: http.MethodGet, Path: "/"},
+			},
+		}); err != nil {
+			return err
+		}
+
+		<-started
+
+		if err := a.SetPoolConfig(&privatevcs.AgentPoolConfig{Host: a.pool.Load().hosts[0], Key: "rotated"}); err != nil {
+			return err
+		}
+
+		for range 2 {
+			res, err := stream.Recv()
+			if err != nil {
+				return err
+			}
+			received <- res
+		}
+
+		return nil
+	})
+
+	var err error
+	a, err = New(&AgentConfig{
+		PoolConfig:         &privatevcs.AgentPoolConfig{Host: host, Key: "original"},
+		TargetBaseEndpoint: target.targetBaseEndpoint,
+		Validator:          new(blocklist.List),
+		HTTPClient:         target.httpClient,
+		DialInsecure:       true,
+	})
+	require.NoError(t, err, "could not create agent")
+
+	// The in-flight request of the rotated stream only completes once the
+	// replacement stream is connected.
+	require.ErrorIs(t, a.Run(spcontext.New(log.NewNopLogger())), ErrPoolRotated)
+	require.NoError(t, a.Run(spcontext.New(log.NewNopLogger())))
+
+	a.Wait()
+
+	require.NotNil(t, (<-received).GetGoingAway())
+	require.Equal(t, "done", string((<-received).GetHttpResponse().GetBody()))
+}
@@ -0,0 +1,194 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/proxy"
+)
+
+// ProxyError is returned when the gateway proxy refuses to open a tunnel to
+// the gateway, as opposed to the proxy itself being unreachable.
+type ProxyError struct {
+	// Proxy is the proxy URL with the password redacted.
+	Proxy string
+
+	// StatusCode is the status the proxy responded with, for HTTP proxies.
+	StatusCode int
+
+	err error
+}
+
+func (e *ProxyError) Error() string {
+	return fmt.Sprintf("gateway proxy %s refused the tunnel: %v", e.Proxy, e.err)
+}
+
+func (e *ProxyError) Unwrap() error {
+	return e.err
+}
+
+// contextDialer opens connections to gateway addresses.
+type contextDialer func(ctx context.Context, address string) (net.Conn, error)
+
+// newProxyDialer returns a dialer tunnelling gateway connections through the
+// proxy at rawURL. Supported schemes are http, using HTTP CONNECT, and socks5.
+// Credentials in the URL are used for basic or SOCKS5 username/password
+// authentication.
+func newProxyDialer(rawURL string) (contextDialer, error) {
+	proxyURL, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid proxy URL")
+	}
+
+	if proxyURL.Hostname() == "" {
+		return nil, errors.Errorf("proxy URL %s has no host", proxyURL.Redacted())
+	}
+
+	switch proxyURL.Scheme {
+	case "http":
+		return func(ctx context.Context, address string) (net.Conn, error) {
+			return dialHTTPProxy(ctx, proxyURL, address)
+		}, nil
+	case "socks5", "socks5h":
+		return func(ctx context.Context, address string) (net.Conn, error) {
+			return dialSOCKS5Proxy(ctx, proxyURL, address)
+		}, nil
+	default:
+		return nil, errors.Errorf("unsupported proxy scheme %q, use http or socks5", proxyURL.Scheme)
+	}
+}
+
+func proxyAddress(proxyURL *url.URL, defaultPort string) string {
+	if port := proxyURL.Port(); port != "" {
+		return net.JoinHostPort(proxyURL.Hostname(), port)
+	}
+
+	return net.JoinHostPort(proxyURL.Hostname(), defaultPort)
+}
+
+func dialHTTPProxy(ctx context.Context, proxyURL *url.URL, address string) (_ net.Conn, outErr error) {
+	var dialer net.Dialer
+
+	conn, err := dialer.DialContext(ctx, "tcp", proxyAddress(proxyURL, "80"))
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't connect to gateway proxy")
+	}
+	defer func() {
+		if outErr != nil {
+			_ = conn.Close()
+		}
+	}()
+
+	// The handshake is bound by the dial deadline, which is lifted once the
+	// tunnel is open.
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return nil, errors.Wrap(err, "couldn't set proxy handshake deadline")
+		}
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: make(http.Header),
+	}
+
+	if user := proxyURL.User; user != nil {
+		password, _ := user.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+
+	if err := req.Write(conn); err != nil {
+		return nil, errors.Wrap(err, "couldn't send CONNECT request to gateway proxy")
+	}
+
+	reader := bufio.NewReader(conn)
+
+	res, err := http.ReadResponse(reader, req)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't read CONNECT response from gateway proxy")
+	}
+	_ = res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		err := errors.Errorf("unexpected response %q", res.Status)
+		if res.StatusCode == http.StatusProxyAuthRequired {
+			err = errors.Errorf("unexpected response %q, check the proxy credentials", res.Status)
+		}
+
+		return nil, &ProxyError{Proxy: proxyURL.Redacted(), StatusCode: res.StatusCode, err: err}
+	}
+
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		return nil, errors.Wrap(err, "couldn't reset proxy handshake deadline")
+	}
+
+	// The proxy can't know what the gateway sends before the tunnel is used,
+	// but nothing forbids it from sending it early.
+	if reader.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, reader: reader}, nil
+	}
+
+	return conn, nil
+}
+
+func dialSOCKS5Proxy(ctx context.Context, proxyURL *url.URL, address string) (net.Conn, error) {
+	var auth *proxy.Auth
+	if user := proxyURL.User; user != nil {
+		password, _ := user.Password()
+		auth = &proxy.Auth{User: user.Username(), Password: password}
+	}
+
+	// The forward dialer records whether the proxy was reached, so that
+	// handshake failures can be told apart from an unreachable proxy.
+	forward := &trackingDialer{}
+
+	dialer, err := proxy.SOCKS5("tcp", proxyAddress(proxyURL, "1080"), auth, forward)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't create SOCKS5 dialer")
+	}
+
+	conn, err := dialer.(proxy.ContextDialer).DialContext(ctx, "tcp", address)
+	if err == nil {
+		return conn, nil
+	}
+
+	if forward.connected {
+		return nil, &ProxyError{Proxy: proxyURL.Redacted(), err: err}
+	}
+
+	return nil, errors.Wrap(err, "couldn't connect to gateway proxy")
+}
+
+type trackingDialer struct {
+	net.Dialer
+
+	connected bool
+}
+
+func (d *trackingDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	conn, err := d.Dialer.DialContext(ctx, network, address)
+	d.connected = err == nil
+	return conn, err
+}
+
+// bufferedConn is a connection whose first bytes have already been read into
+// a buffer.
+type bufferedConn struct {
+	net.Conn
+
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
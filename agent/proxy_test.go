@@ -0,0 +1,135 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/pkg/errors"
+	"github.com/spacelift-io/spcontext"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacelift-io/vcs-agent/privatevcs"
+	"github.com/spacelift-io/vcs-agent/privatevcs/validation/blocklist"
+)
+
+// startTestProxy starts an HTTP CONNECT proxy on a random local port, which
+// only opens tunnels for requests authenticated as user:password.
+func startTestProxy(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err, "could not listen")
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+
+				req, err := http.ReadRequest(bufio.NewReader(conn))
+				if err != nil {
+					return
+				}
+
+				if req.Header.Get("Proxy-Authorization") != "Basic "+base64.StdEncoding.EncodeToString([]byte("user:password")) {
+					_, _ = io.WriteString(conn, "HTTP/1.1 407 Proxy Authentication Required\r\n\r\n")
+					return
+				}
+
+				upstream, err := net.Dial("tcp", req.Host)
+				if err != nil {
+					_, _ = io.WriteString(conn, "HTTP/1.1 502 Bad Gateway\r\n\r\n")
+					return
+				}
+				defer upstream.Close()
+
+				_, _ = io.WriteString(conn, "HTTP/1.1 200 Connection Established\r\n\r\n")
+
+				go func() { _, _ = io.Copy(upstream, conn) }()
+				_, _ = io.Copy(conn, upstream)
+			}()
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+func TestRunThroughGatewayProxy(t *testing.T) {
+	proxy := startTestProxy(t)
+
+	var connected bool
+	host := startTestGateway(t, func(stream privatevcs.Gateway_ConnectServer) error {
+		connected = true
+		return nil
+	})
+
+	newAgent := func(proxyURL string) *Agent {
+		a, err := New(&AgentConfig{
+			PoolConfig:         &privatevcs.AgentPoolConfig{Host: host},
+			TargetBaseEndpoint: "https://gitlab.example.com",
+			Validator:          new(blocklist.List),
+			GatewayProxy:       proxyURL,
+			DialInsecure:       true,
+		})
+		require.NoError(t, err, "could not create agent")
+
+		return a
+	}
+
+	t.Run("with valid credentials", func(t *testing.T) {
+		require.NoError(t, newAgent("http://user:password@"+proxy).Run(spcontext.New(log.NewNopLogger())))
+		require.True(t, connected)
+	})
+
+	t.Run("with invalid credentials", func(t *testing.T) {
+		err := newAgent("http://user:wrong@" + proxy).Run(spcontext.New(log.NewNopLogger()))
+
+		var proxyErr *ProxyError
+		require.True(t, errors.As(err, &proxyErr), "expected a proxy error, got %v", err)
+		require.Equal(t, http.StatusProxyAuthRequired, proxyErr.StatusCode)
+		require.Equal(t, "http://user:xxxxx@"+proxy, proxyErr.Proxy)
+	})
+}
+
+func TestSOCKS5ProxyRefusal(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err, "could not listen")
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		// Reject every authentication method offered.
+		greeting := make([]byte, 3)
+		if _, err := io.ReadFull(conn, greeting); err == nil {
+			_, _ = conn.Write([]byte{0x05, 0xff})
+		}
+	}()
+
+	dialer, err := newProxyDialer("socks5://" + listener.Addr().String())
+	require.NoError(t, err, "could not create dialer")
+
+	_, err = dialer(context.Background(), "gateway.example.com:1983")
+
+	var proxyErr *ProxyError
+	require.True(t, errors.As(err, &proxyErr), "expected a proxy error, got %v", err)
+}
+
+func TestNewProxyDialer(t *testing.T) {
+	_, err := newProxyDialer("ftp://proxy:21")
+
+	require.EqualError(t, err, `unsupported proxy scheme "ftp", use http or socks5`)
+}
@@ -0,0 +1,163 @@
+package agent
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spacelift-io/spcontext"
+
+	"github.com/spacelift-io/vcs-agent/privatevcs"
+	"github.com/spacelift-io/vcs-agent/privatevcs/validation"
+)
+
+// RedirectMode controls how redirects returned by the target are handled.
+type RedirectMode string
+
+const (
+	// RedirectModeFollow follows redirects to the target and the allowed
+	// hosts, validating each hop.
+	RedirectModeFollow RedirectMode = "follow"
+
+	// RedirectModeReturn returns redirects to the gateway without following
+	// them.
+	RedirectModeReturn RedirectMode = "return"
+)
+
+const defaultMaxRedirects = 10
+
+// noRedirects makes an http.Client return redirects instead of following them,
+// so that the agent can apply its own policy.
+func noRedirects(*http.Request, []*http.Request) error {
+	return http.ErrUseLastResponse
+}
+
+// do performs the request, following redirects according to the redirect
+// policy. Each hop must go to the target or one of the allowed hosts, and hops
+// to the target are validated like the original request.
+func (a *Agent) do(ctx *spcontext.Context, target *url.URL, req *http.Request, body []byte) (*http.Response, error) {
+	for hops := 0; ; hops++ {
+		res, err := a.httpClient.Do(req)
+		if err != nil || a.redirectMode == RedirectModeReturn {
+			return res, err
+		}
+
+		location := res.Header.Get("Location")
+		if !isRedirect(res.StatusCode) || location == "" {
+			return res, nil
+		}
+
+		// The body of the redirect response isn't used.
+		_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 1<<16))
+		_ = res.Body.Close()
+
+		if hops >= a.maxRedirects {
+			return nil, redirectBlockedError(errors.Errorf("stopped after %d redirects", a.maxRedirects))
+		}
+
+		next, err := a.redirectRequest(ctx, target, req, res.StatusCode, location, body)
+		if err != nil {
+			return nil, err
+		}
+
+		ctx.With(
+			"status_code", res.StatusCode,
+			"location", next.URL.Redacted(),
+		).Debugf("Following redirect.")
+
+		req = next
+	}
+}
+
+func isRedirect(status int) bool {
+	switch status {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	default:
+		return false
+	}
+}
+
+// redirectRequest builds the request for the next hop, following the method
+// and header semantics of http.Client.
+func (a *Agent) redirectRequest(ctx *spcontext.Context, target *url.URL, req *http.Request, status int, location string, body []byte) (*http.Request, error) {
+	u, err := req.URL.Parse(location)
+	if err != nil {
+		return nil, redirectBlockedError(errors.Wrapf(err, "invalid redirect location %q", location))
+	}
+
+	onTarget := a.checkTargetHost(target, u, "") == nil
+	if !onTarget && !a.isAllowedRedirectHost(target, u) {
+		return nil, redirectBlockedError(errors.Errorf("redirect to %s://%s is not allowed", u.Scheme, u.Host))
+	}
+
+	method, reqBody := req.Method, body
+	if status == http.StatusSeeOther || ((status == http.StatusMovedPermanently || status == http.StatusFound) && req.Method == http.MethodPost) {
+		method, reqBody = http.MethodGet, nil
+		if req.Method == http.MethodHead {
+			method = http.MethodHead
+		}
+	}
+
+	next, err := http.NewRequestWithContext(req.Context(), method, u.String(), bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, redirectBlockedError(errors.Wrap(err, "couldn't create redirect request"))
+	}
+
+	next.Header = req.Header.Clone()
+	if reqBody == nil {
+		next.Header.Del("Content-Type")
+		next.Header.Del("Content-Length")
+	}
+
+	// Credentials for the target must not leak to other hosts, such as object
+	// storage serving pre-signed URLs.
+	if !onTarget {
+		next.Header.Del("Authorization")
+		next.Header.Del("Private-Token")
+		next.Header.Del("Cookie")
+		return next, nil
+	}
+
+	if _, err := a.validator.Validate(ctx, a.vendor, next); err != nil {
+		var validationErr *validation.Error
+		if !errors.As(err, &validationErr) {
+			err = &requestError{code: privatevcs.ErrorCode_ERROR_CODE_REQUEST_BLOCKED, err: err}
+		}
+		return nil, errors.Wrap(err, "redirect blocked")
+	}
+
+	return next, nil
+}
+
+// isAllowedRedirectHost returns whether the URL is on one of the hosts
+// redirects may go to besides the target. Patterns are host names, optionally
+// with a port, or "*." followed by a domain matching all of its subdomains.
+// Redirects to them must not downgrade to plain HTTP.
+func (a *Agent) isAllowedRedirectHost(target *url.URL, u *url.URL) bool {
+	if u.User != nil || (u.Scheme != "https" && u.Scheme != target.Scheme) {
+		return false
+	}
+
+	host, hostname := strings.ToLower(u.Host), strings.ToLower(u.Hostname())
+
+	for _, pattern := range a.redirectAllowedHosts {
+		if domain, ok := strings.CutPrefix(pattern, "*."); ok {
+			if strings.HasSuffix(hostname, "."+domain) {
+				return true
+			}
+		} else if pattern == host || pattern == hostname {
+			return true
+		}
+	}
+
+	return false
+}
+
+func redirectBlockedError(err error) error {
+	return &requestError{code: privatevcs.ErrorCode_ERROR_CODE_REDIRECT_BLOCKED, err: err}
+}
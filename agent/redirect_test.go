@@ -0,0 +1,122 @@
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacelift-io/vcs-agent/privatevcs"
+	"github.com/spacelift-io/vcs-agent/privatevcs/validation/blocklist"
+)
+
+func TestHandleRequestRedirects(t *testing.T) {
+	var storageAuthorization []string
+	storage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		storageAuthorization = append(storageAuthorization, r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte("archive"))
+	}))
+	t.Cleanup(storage.Close)
+
+	a := newTestAgent(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/moved":
+			http.Redirect(w, r, "/new", http.StatusFound)
+		case "/archive":
+			http.Redirect(w, r, storage.URL+"/archive.tar.gz", http.StatusFound)
+		case "/blocked-redirect":
+			http.Redirect(w, r, "/blocked", http.StatusFound)
+		case "/loop":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		default:
+			_, _ = w.Write([]byte(r.URL.Path))
+		}
+	})
+
+	get := func(path string) *privatevcs.Response {
+		responses := collectResponses(t, a, &privatevcs.HTTPRequest{
+			Method:  http.MethodGet,
+			Path:    path,
+			Headers: map[string]string{"Authorization": "Bearer secret"},
+		})
+		require.Len(t, responses, 1)
+		return responses[0]
+	}
+
+	t.Run("to the target", func(t *testing.T) {
+		require.Equal(t, "/new", string(get("/moved").GetHttpResponse().GetBody()))
+	})
+
+	t.Run("to a host which isn't allowed", func(t *testing.T) {
+		response := get("/archive")
+
+		require.Equal(t, privatevcs.ErrorCode_ERROR_CODE_REDIRECT_BLOCKED, response.GetErrorDetails().GetCode())
+		require.Empty(t, storageAuthorization, "the storage shouldn't be reached")
+	})
+
+	t.Run("to an allowed host", func(t *testing.T) {
+		storageURL, err := url.Parse(storage.URL)
+		require.NoError(t, err)
+		a.redirectAllowedHosts = []string{storageURL.Host}
+		t.Cleanup(func() { a.redirectAllowedHosts = nil })
+
+		require.Equal(t, "archive", string(get("/archive").GetHttpResponse().GetBody()))
+		require.Equal(t, []string{""}, storageAuthorization, "credentials shouldn't leak to other hosts")
+	})
+
+	t.Run("to a path blocked by the validator", func(t *testing.T) {
+		list := &blocklist.List{Rules: []*blocklist.Rule{{Name: "Blocked", Method: ".*", Path: "^/blocked$"}}}
+		require.NoError(t, list.Compile())
+		a.validator = list
+		t.Cleanup(func() { a.validator = new(blocklist.List) })
+
+		response := get("/blocked-redirect")
+
+		require.Equal(t, privatevcs.ErrorCode_ERROR_CODE_BLOCKED_BY_RULE, response.GetErrorDetails().GetCode())
+		require.Equal(t, "Blocked", response.GetErrorDetails().GetRule())
+	})
+
+	t.Run("in a loop", func(t *testing.T) {
+		response := get("/loop")
+
+		require.Equal(t, privatevcs.ErrorCode_ERROR_CODE_REDIRECT_BLOCKED, response.GetErrorDetails().GetCode())
+		require.Contains(t, response.GetError(), "stopped after 10 redirects")
+	})
+
+	t.Run("when returning redirects", func(t *testing.T) {
+		a.redirectMode = RedirectModeReturn
+		t.Cleanup(func() { a.redirectMode = RedirectModeFollow })
+
+		response := get("/moved").GetHttpResponse()
+
+		require.EqualValues(t, http.StatusFound, response.GetStatus())
+		require.Equal(t, "/new", response.GetHeaders()["Location"])
+	})
+}
+
+func TestIsAllowedRedirectHost(t *testing.T) {
+	target, err := url.Parse("https://github.example.com")
+	require.NoError(t, err)
+
+	a := &Agent{redirectAllowedHosts: []string{"storage.example.com", "*.blob.example.net"}}
+
+	for raw, expected := range map[string]bool{
+		"https://storage.example.com/x":         true,
+		"https://STORAGE.example.com:443/x":     true,
+		"https://account.blob.example.net/x":    true,
+		"https://blob.example.net/x":            false,
+		"https://evilblob.example.net/x":        false,
+		"http://storage.example.com/x":          false,
+		"https://user@storage.example.com/x":    false,
+		"https://storage.example.com.evil.io/x": false,
+	} {
+		t.Run(raw, func(t *testing.T) {
+			u, err := url.Parse(raw)
+			require.NoError(t, err)
+
+			require.Equal(t, expected, a.isAllowedRedirectHost(target, u))
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package agent
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/spacelift-io/vcs-agent/privatevcs"
+	"github.com/spacelift-io/vcs-agent/privatevcs/validation"
+)
+
+// parseTargetBaseEndpoint parses the base endpoint of the target, which must be
+// an absolute http or https URL without a query.
+func parseTargetBaseEndpoint(raw string) (*url.URL, error) {
+	out, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if (out.Scheme != "http" && out.Scheme != "https") || out.Host == "" {
+		return nil, errors.Errorf("%q is not an absolute http or https URL", raw)
+	}
+
+	if out.User != nil || out.RawQuery != "" || out.Fragment != "" {
+		return nil, errors.Errorf("%q must not contain credentials, a query or a fragment", raw)
+	}
+
+	return out, nil
+}
+
+// targetURL builds the URL of a request to the target from the path sent by
+// the gateway. The path is parsed on its own and appended to the base
+// endpoint's path, so that it can't change the scheme or host the request is
+// sent to, unlike with string concatenation where e.g. "@evil.internal/x"
+// would.
+func (a *Agent) targetURL(path string) (*url.URL, error) {
+	base, err := parseTargetBaseEndpoint(a.targetBaseEndpoint)
+	if err != nil {
+		return nil, invalidTargetURLError(err)
+	}
+
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.Contains(path, `\`) {
+		return nil, invalidTargetURLError(errors.Errorf("path %q must be absolute and must not contain a host", path))
+	}
+
+	ref, err := url.Parse(path)
+	if err != nil {
+		return nil, invalidTargetURLError(errors.Wrapf(err, "invalid path %q", path))
+	}
+
+	if ref.Scheme != "" || ref.Host != "" || ref.User != nil || ref.Opaque != "" || ref.Fragment != "" {
+		return nil, invalidTargetURLError(errors.Errorf("path %q must only contain a path and a query", path))
+	}
+
+	prefix := strings.TrimSuffix(base.Path, "/")
+	rawPrefix := strings.TrimSuffix(base.EscapedPath(), "/")
+
+	out := &url.URL{
+		Scheme:   base.Scheme,
+		Host:     base.Host,
+		Path:     prefix + ref.Path,
+		RawQuery: ref.RawQuery,
+	}
+
+	// The raw path keeps escapes like %2F in GitLab project IDs intact.
+	if rawPath := rawPrefix + ref.EscapedPath(); rawPath != out.Path {
+		out.RawPath = rawPath
+	}
+
+	return out, nil
+}
+
+// checkTargetHost verifies that the request is still sent to the target once
+// it has been rewritten. GitHub Enterprise tarballs may also be downloaded from
+// the codeload subdomain of the target.
+func (a *Agent) checkTargetHost(target *url.URL, actual *url.URL, host string) error {
+	allowed := []string{target.Host}
+	if a.vendor == validation.GitHubEnterprise {
+		allowed = append(allowed, "codeload."+target.Host)
+	}
+
+	if actual.Scheme == target.Scheme && actual.User == nil && (host == "" || host == actual.Host) {
+		for _, candidate := range allowed {
+			if strings.EqualFold(actual.Host, candidate) {
+				return nil
+			}
+		}
+	}
+
+	return invalidTargetURLError(errors.Errorf("request to %s://%s is not allowed, only the target %s can be reached", actual.Scheme, actual.Host, target.Host))
+}
+
+func invalidTargetURLError(err error) error {
+	return &requestError{
+		code: privatevcs.ErrorCode_ERROR_CODE_INVALID_REQUEST,
+		err:  errors.Wrap(err, "invalid request URL"),
+	}
+}
@@ -0,0 +1,83 @@
+package agent
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacelift-io/vcs-agent/privatevcs"
+	"github.com/spacelift-io/vcs-agent/privatevcs/validation"
+)
+
+func TestTargetURL(t *testing.T) {
+	a := &Agent{targetBaseEndpoint: "https://git.example.com/gitlab"}
+
+	t.Run("with valid paths", func(t *testing.T) {
+		for path, expected := range map[string]string{
+			"/api/v4/projects":                        "https://git.example.com/gitlab/api/v4/projects",
+			"/api/v4/projects/group%2Fproject":        "https://git.example.com/gitlab/api/v4/projects/group%2Fproject",
+			"/api/v4/projects?search=a&per_page=100":  "https://git.example.com/gitlab/api/v4/projects?search=a&per_page=100",
+			"/api/v4/projects/%40evil.internal/files": "https://git.example.com/gitlab/api/v4/projects/%40evil.internal/files",
+		} {
+			t.Run(path, func(t *testing.T) {
+				out, err := a.targetURL(path)
+
+				require.NoError(t, err)
+				require.Equal(t, expected, out.String())
+			})
+		}
+	})
+
+	t.Run("with malicious paths", func(t *testing.T) {
+		for _, path := range []string{
+			"@evil.internal/x",
+			".attacker.example/x",
+			":8080/x",
+			"//evil.internal/x",
+			`/\evil.internal/x`,
+			"https://evil.internal/x",
+			"/x#fragment",
+			"",
+		} {
+			t.Run(path, func(t *testing.T) {
+				_, err := a.targetURL(path)
+
+				require.Error(t, err)
+				require.Equal(t, privatevcs.ErrorCode_ERROR_CODE_INVALID_REQUEST, errorDetails(err).GetCode())
+			})
+		}
+	})
+}
+
+func TestCheckTargetHost(t *testing.T) {
+	target, err := url.Parse("https://github.example.com")
+	require.NoError(t, err)
+
+	check := func(vendor validation.Vendor, raw string) error {
+		actual, err := url.Parse(raw)
+		require.NoError(t, err)
+
+		return (&Agent{vendor: vendor}).checkTargetHost(target, actual, actual.Host)
+	}
+
+	require.NoError(t, check(validation.GitHubEnterprise, "https://github.example.com/x"))
+	require.NoError(t, check(validation.GitHubEnterprise, "https://codeload.github.example.com/x"))
+	require.Error(t, check(validation.GitLab, "https://codeload.github.example.com/x"), "only GitHub Enterprise uses codeload")
+	require.Error(t, check(validation.GitHubEnterprise, "http://github.example.com/x"), "the scheme must not change")
+	require.Error(t, check(validation.GitHubEnterprise, "https://github.example.com.evil.internal/x"))
+}
+
+func TestHandleRequestMaliciousPath(t *testing.T) {
+	var reached bool
+	a := newTestAgent(t, func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+	})
+
+	responses := collectResponses(t, a, &privatevcs.HTTPRequest{Method: http.MethodGet, Path: "@evil.internal/x"})
+
+	require.False(t, reached, "the target shouldn't be reached")
+	require.Len(t, responses, 1)
+	require.Equal(t, privatevcs.ErrorCode_ERROR_CODE_INVALID_REQUEST, responses[0].GetErrorDetails().GetCode())
+}
@@ -0,0 +1,52 @@
+package agent
+
+import (
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/spacelift-io/vcs-agent/privatevcs"
+	"github.com/spacelift-io/vcs-agent/privatevcs/validation/allowlist"
+)
+
+const defaultHTTPTimeout = 25 * time.Second
+
+// requestTimeout returns the timeout for the request. Endpoint overrides take
+// precedence over the timeout requested by the gateway, which in turn takes
+// precedence over the default. The result never exceeds the maximum timeout,
+// if one is set.
+func (a *Agent) requestTimeout(req *http.Request, msg *privatevcs.HTTPRequest) time.Duration {
+	timeout := a.httpTimeout
+
+	maxTimeout := time.Duration(math.MaxInt64)
+	if a.httpMaxTimeout > 0 {
+		maxTimeout = a.httpMaxTimeout
+	}
+
+	if msg.TimeoutMillis > 0 {
+		// Clamped before converting, as large values would overflow.
+		timeout = maxTimeout
+		if msg.TimeoutMillis < int64(maxTimeout/time.Millisecond) {
+			timeout = time.Duration(msg.TimeoutMillis) * time.Millisecond
+		}
+	}
+
+	if len(a.httpEndpointTimeouts) > 0 {
+		var patterns *allowlist.Patterns
+		if a.allowlistPatterns != nil {
+			patterns = a.allowlistPatterns()
+		}
+
+		if name, _, err := patterns.MatchRequest(a.vendor, req); err == nil {
+			if override, ok := a.httpEndpointTimeouts[name]; ok {
+				timeout = override
+			}
+		}
+	}
+
+	if timeout > maxTimeout {
+		timeout = maxTimeout
+	}
+
+	return timeout
+}
@@ -0,0 +1,107 @@
+package agent
+
+import (
+	"math"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacelift-io/vcs-agent/privatevcs"
+	"github.com/spacelift-io/vcs-agent/privatevcs/validation"
+	"github.com/spacelift-io/vcs-agent/privatevcs/validation/allowlist"
+)
+
+func TestRequestTimeout(t *testing.T) {
+	const (
+		tarballPath   = "/api/v4/projects/octocats%2Finfra/repository/archive"
+		languagesPath = "/api/v4/projects/octocats%2Finfra/languages"
+	)
+
+	patterns := &allowlist.Patterns{Patterns: []*allowlist.Pattern{{
+		Name:   "Get Repository Languages",
+		Method: http.MethodGet,
+		Path:   "^/api/v4/projects/(?P<project>[^/]+)/languages$",
+	}}}
+	require.NoError(t, patterns.Compile(validation.GitLab), "could not compile patterns")
+
+	testCases := []struct {
+		name            string
+		path            string
+		requested       time.Duration
+		requestedMillis int64
+		maxTimeout      time.Duration
+		expectedResult  time.Duration
+	}{
+		{
+			name:           "with defaults",
+			path:           "/api/v4/user",
+			expectedResult: 25 * time.Second,
+		},
+		{
+			name:           "with a timeout requested by the gateway",
+			path:           "/api/v4/user",
+			requested:      time.Minute,
+			expectedResult: time.Minute,
+		},
+		{
+			name:           "with an endpoint override",
+			path:           tarballPath,
+			requested:      time.Minute,
+			expectedResult: 10 * time.Minute,
+		},
+		{
+			name:           "with a maximum timeout",
+			path:           tarballPath,
+			maxTimeout:     5 * time.Minute,
+			expectedResult: 5 * time.Minute,
+		},
+		{
+			name:           "with an override of a user-defined endpoint",
+			path:           languagesPath,
+			requested:      time.Minute,
+			expectedResult: 2 * time.Minute,
+		},
+		{
+			name:            "with an overflowing timeout requested by the gateway",
+			path:            "/api/v4/user",
+			requestedMillis: math.MaxInt64 / 2,
+			maxTimeout:      5 * time.Minute,
+			expectedResult:  5 * time.Minute,
+		},
+		{
+			name:            "with an overflowing timeout and no maximum",
+			path:            "/api/v4/user",
+			requestedMillis: math.MaxInt64,
+			expectedResult:  time.Duration(math.MaxInt64),
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			sut := &Agent{
+				vendor:         validation.GitLab,
+				httpTimeout:    defaultHTTPTimeout,
+				httpMaxTimeout: testCase.maxTimeout,
+				httpEndpointTimeouts: map[string]time.Duration{
+					"Get Repository Tarball":   10 * time.Minute,
+					"Get Repository Languages": 2 * time.Minute,
+				},
+				allowlistPatterns: func() *allowlist.Patterns { return patterns },
+			}
+
+			req, err := http.NewRequest(http.MethodGet, "https://gitlab.example.com"+testCase.path, nil)
+			require.NoError(t, err, "could not create request")
+
+			millis := testCase.requested.Milliseconds()
+			if testCase.requestedMillis != 0 {
+				millis = testCase.requestedMillis
+			}
+
+			timeout := sut.requestTimeout(req, &privatevcs.HTTPRequest{TimeoutMillis: millis})
+
+			require.Equal(t, testCase.expectedResult, timeout)
+		})
+	}
+}
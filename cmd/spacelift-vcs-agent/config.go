@@ -0,0 +1,328 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v3"
+	"gopkg.in/yaml.v3"
+
+	"github.com/spacelift-io/vcs-agent/agent"
+	"github.com/spacelift-io/vcs-agent/privatevcs/validation"
+	"github.com/spacelift-io/vcs-agent/privatevcs/validation/allowlist"
+	"github.com/spacelift-io/vcs-agent/transport"
+)
+
+// targetConfig describes a VCS target served by the agent through its own
+// agent pool. A single target is built from the flags, or several are read
+// from the configuration file, with the flags providing defaults.
+type targetConfig struct {
+	Name               string `yaml:"name"`
+	Vendor             string `yaml:"vendor"`
+	TargetBaseEndpoint string `yaml:"target_base_endpoint"`
+	Token              string `yaml:"token"`
+	TokenFile          string `yaml:"token_file"`
+	Parallelism        int    `yaml:"parallelism"`
+	StreamConcurrency  int    `yaml:"stream_concurrency"`
+
+	AllowedProjects     string   `yaml:"allowed_projects"`
+	AllowedProjectsSet  bool     `yaml:"-"`
+	UseAllowlist        bool     `yaml:"use_allowlist"`
+	AllowlistPath       string   `yaml:"allowlist_path"`
+	BlocklistPath       string   `yaml:"blocklist_path"`
+	ValidationOrder     []string `yaml:"validation_order"`
+	ValidationChainMode string   `yaml:"validation_chain_mode"`
+
+	CACert                   string   `yaml:"ca_cert"`
+	CACertPaths              []string `yaml:"ca_cert_paths"`
+	CACertExcludeSystemRoots bool     `yaml:"ca_cert_exclude_system_roots"`
+	ClientCert               string   `yaml:"client_cert"`
+	ClientKey                string   `yaml:"client_key"`
+	ClientCertPath           string   `yaml:"client_cert_path"`
+	ClientKeyPath            string   `yaml:"client_key_path"`
+	ClientKeyPassword        string   `yaml:"client_key_password"`
+
+	HTTPDisableResponseCompression bool              `yaml:"http_disable_response_compression"`
+	HTTPResponseChunkSize          int               `yaml:"http_response_chunk_size"`
+	HTTPTimeout                    time.Duration     `yaml:"http_timeout"`
+	HTTPMaxTimeout                 time.Duration     `yaml:"http_max_timeout"`
+	HTTPEndpointTimeouts           map[string]string `yaml:"http_endpoint_timeouts"`
+	HTTPRedirectMode               string            `yaml:"http_redirect_mode"`
+	HTTPRedirectAllowedHosts       []string          `yaml:"http_redirect_allowed_hosts"`
+	HTTPMaxRedirects               int               `yaml:"http_max_redirects"`
+	HTTPMaxIdleConns               int               `yaml:"http_max_idle_conns"`
+	HTTPMaxIdleConnsPerHost        int               `yaml:"http_max_idle_conns_per_host"`
+	HTTPTLSMinVersion              string            `yaml:"http_tls_min_version"`
+	HTTPDisableHTTP2               bool              `yaml:"http_disable_http2"`
+	HTTPEgressAllowedRanges        []string          `yaml:"http_egress_allowed_ranges"`
+	DebugPrintAll                  bool              `yaml:"debug_print_all"`
+}
+
+// targetConfigFromFlags builds the target configured with flags.
+func targetConfigFromFlags(cmd *cli.Command) *targetConfig {
+	return &targetConfig{
+		Vendor:             cmd.String(flagVCSVendor.Name),
+		TargetBaseEndpoint: cmd.String(flagTargetBaseEndpoint.Name),
+		Token:              cmd.String(flagPoolToken.Name),
+		TokenFile:          cmd.String(flagPoolTokenFile.Name),
+		Parallelism:        cmd.Int(flagParallelism.Name),
+		StreamConcurrency:  cmd.Int(flagStreamConcurrency.Name),
+
+		AllowedProjects:     cmd.String(flagAllowedProjects.Name),
+		AllowedProjectsSet:  cmd.IsSet(flagAllowedProjects.Name),
+		UseAllowlist:        cmd.Bool(flagUseAllowlist.Name),
+		AllowlistPath:       cmd.String(flagAllowlistPath.Name),
+		BlocklistPath:       cmd.String(flagBlocklistPath.Name),
+		ValidationOrder:     cmd.StringSlice(flagValidationOrder.Name),
+		ValidationChainMode: cmd.String(flagValidationChainMode.Name),
+
+		CACert:                   cmd.String(flagCACert.Name),
+		CACertPaths:              cmd.StringSlice(flagCACertPath.Name),
+		CACertExcludeSystemRoots: cmd.Bool(flagCACertExcludeSystemRoots.Name),
+		ClientCert:               cmd.String(flagClientCert.Name),
+		ClientKey:                cmd.String(flagClientKey.Name),
+		ClientCertPath:           cmd.String(flagClientCertPath.Name),
+		ClientKeyPath:            cmd.String(flagClientKeyPath.Name),
+		ClientKeyPassword:        cmd.String(flagClientKeyPassword.Name),
+
+		HTTPDisableResponseCompression: cmd.Bool(flagHTTPDisableResponseCompression.Name),
+		HTTPResponseChunkSize:          cmd.Int(flagHTTPResponseChunkSize.Name),
+		HTTPTimeout:                    cmd.Duration(flagHTTPTimeout.Name),
+		HTTPMaxTimeout:                 cmd.Duration(flagHTTPMaxTimeout.Name),
+		HTTPEndpointTimeouts:           cmd.StringMap(flagHTTPEndpointTimeouts.Name),
+		HTTPRedirectMode:               cmd.String(flagHTTPRedirectMode.Name),
+		HTTPRedirectAllowedHosts:       cmd.StringSlice(flagHTTPRedirectAllowedHosts.Name),
+		HTTPMaxRedirects:               cmd.Int(flagHTTPMaxRedirects.Name),
+		HTTPMaxIdleConns:               cmd.Int(flagHTTPMaxIdleConns.Name),
+		HTTPMaxIdleConnsPerHost:        cmd.Int(flagHTTPMaxIdleConnsPerHost.Name),
+		HTTPTLSMinVersion:              cmd.String(flagHTTPTLSMinVersion.Name),
+		HTTPDisableHTTP2:               cmd.Bool(flagHTTPDisableHTTP2.Name),
+		HTTPEgressAllowedRanges:        cmd.StringSlice(flagHTTPEgressAllowedRanges.Name),
+		DebugPrintAll:                  cmd.Bool(flagDebugPrintAll.Name),
+	}
+}
+
+// validateBackoff checks the reconnect settings, which are shared by all
+// targets.
+func validateBackoff(backoff agent.Backoff) error {
+	if backoff.Initial <= 0 {
+		return errors.New("--reconnect-initial-delay must be positive")
+	}
+
+	if backoff.Max < backoff.Initial {
+		return errors.New("--reconnect-max-delay must be at least --reconnect-initial-delay")
+	}
+
+	if backoff.Multiplier < 1 {
+		return errors.New("--reconnect-multiplier must be at least 1")
+	}
+
+	if backoff.Jitter < 0 || backoff.Jitter > 1 {
+		return errors.New("--reconnect-jitter must be between 0 and 1")
+	}
+
+	if backoff.ResetAfter <= 0 {
+		return errors.New("--reconnect-reset-after must be positive")
+	}
+
+	return nil
+}
+
+// loadTargetConfigs reads the targets from the configuration file. Settings
+// missing from a target are taken from defaults, except for its pool token.
+func loadTargetConfigs(path string, defaults *targetConfig) ([]*targetConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read config file: %w", err)
+	}
+
+	var file struct {
+		Targets []yaml.Node `yaml:"targets"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid config file %q: %w", path, err)
+	}
+
+	if len(file.Targets) == 0 {
+		return nil, fmt.Errorf("invalid config file %q: no targets defined", path)
+	}
+
+	out := make([]*targetConfig, 0, len(file.Targets))
+	names := make(map[string]bool)
+
+	for i, node := range file.Targets {
+		config := defaults.clone()
+		config.Token, config.TokenFile = "", ""
+
+		if err := node.Decode(config); err != nil {
+			return nil, fmt.Errorf("invalid config file %q: invalid target %d: %w", path, i, err)
+		}
+
+		if hasKey(&node, "allowed_projects") {
+			config.AllowedProjectsSet = true
+		}
+
+		if config.Name == "" {
+			return nil, fmt.Errorf("invalid config file %q: target %d has no name", path, i)
+		}
+
+		if names[config.Name] {
+			return nil, fmt.Errorf("invalid config file %q: duplicate target name %q", path, config.Name)
+		}
+		names[config.Name] = true
+
+		out = append(out, config)
+	}
+
+	return out, nil
+}
+
+// hasKey returns whether the mapping node sets the key.
+func hasKey(node *yaml.Node, key string) bool {
+	if node.Kind != yaml.MappingNode {
+		return false
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *targetConfig) clone() *targetConfig {
+	out := *c
+	out.ValidationOrder = slices.Clone(c.ValidationOrder)
+	out.CACertPaths = slices.Clone(c.CACertPaths)
+	out.HTTPRedirectAllowedHosts = slices.Clone(c.HTTPRedirectAllowedHosts)
+	out.HTTPEgressAllowedRanges = slices.Clone(c.HTTPEgressAllowedRanges)
+	out.HTTPEndpointTimeouts = maps.Clone(c.HTTPEndpointTimeouts)
+	return &out
+}
+
+// validate checks the settings which are only validated when building the
+// agent, so that all targets are checked before any of them starts.
+func (c *targetConfig) validate() error {
+	if !slices.Contains(availableVendors, c.Vendor) {
+		return fmt.Errorf("invalid vendor specified: '%s', available vendors: [%s]", c.Vendor, strings.Join(availableVendors, ", "))
+	}
+
+	if c.TargetBaseEndpoint == "" {
+		return errors.New("target base endpoint is required")
+	}
+
+	if (c.Token == "") == (c.TokenFile == "") {
+		return errors.New("exactly one of the pool token and the pool token file is required")
+	}
+
+	if c.Parallelism < 1 {
+		return errors.New("parallelism must be at least 1")
+	}
+
+	if c.AllowedProjectsSet && c.Vendor == vendorGitHubEnterprise {
+		return errors.New("allowed projects are not currently supported for the GitHub Enterprise integration")
+	}
+
+	if c.AllowlistPath != "" && !c.UseAllowlist {
+		return errors.New("the allowlist file requires the allowlist to be enabled")
+	}
+
+	if err := c.validateValidationOrder(); err != nil {
+		return err
+	}
+
+	switch validation.ChainMode(c.ValidationChainMode) {
+	case validation.ChainModeAll, validation.ChainModeFirst:
+	default:
+		return fmt.Errorf("invalid validation chain mode %q, use %q or %q", c.ValidationChainMode, validation.ChainModeAll, validation.ChainModeFirst)
+	}
+
+	return nil
+}
+
+// validateValidationOrder checks that the validation order lists each enabled
+// strategy once.
+func (c *targetConfig) validateValidationOrder() error {
+	seen := make(map[string]bool)
+
+	for _, mode := range c.validationOrder() {
+		if mode != validationModeAllowlist && mode != validationModeBlocklist {
+			return fmt.Errorf("invalid validation order entry %q, use %q or %q", mode, validationModeAllowlist, validationModeBlocklist)
+		}
+
+		if seen[mode] {
+			return fmt.Errorf("duplicate validation order entry %q", mode)
+		}
+		seen[mode] = true
+	}
+
+	if c.UseAllowlist && !seen[validationModeAllowlist] {
+		return errors.New("the allowlist is enabled but missing from the validation order")
+	}
+
+	if c.BlocklistPath != "" && !seen[validationModeBlocklist] {
+		return errors.New("the blocklist is enabled but missing from the validation order")
+	}
+
+	return nil
+}
+
+func (c *targetConfig) transportConfig() *transport.Config {
+	return &transport.Config{
+		CACertBase64:       c.CACert,
+		CACertPaths:        c.CACertPaths,
+		ExcludeSystemRoots: c.CACertExcludeSystemRoots,
+		ClientCertificate: transport.ClientCertificate{
+			CertBase64:  c.ClientCert,
+			KeyBase64:   c.ClientKey,
+			CertPath:    c.ClientCertPath,
+			KeyPath:     c.ClientKeyPath,
+			KeyPassword: c.ClientKeyPassword,
+		},
+		MaxIdleConns:        c.HTTPMaxIdleConns,
+		MaxIdleConnsPerHost: c.HTTPMaxIdleConnsPerHost,
+		TLSMinVersion:       c.HTTPTLSMinVersion,
+		DisableHTTP2:        c.HTTPDisableHTTP2,
+		EgressAllowedRanges: c.HTTPEgressAllowedRanges,
+	}
+}
+
+// parseEndpointTimeouts parses timeouts keyed by allowlist endpoint names,
+// making sure the names are known for the vendor, either built-in or from the
+// user-defined patterns.
+func parseEndpointTimeouts(vendor validation.Vendor, patterns *allowlist.Patterns, raw map[string]string) (map[string]time.Duration, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	known := patterns.EndpointNames(vendor)
+	out := make(map[string]time.Duration, len(raw))
+
+	for name, value := range raw {
+		name = strings.TrimSpace(name)
+
+		if !slices.Contains(known, name) {
+			return nil, fmt.Errorf("unknown endpoint %q, known endpoints: %s", name, strings.Join(known, ", "))
+		}
+
+		timeout, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout for endpoint %q: %w", name, err)
+		}
+
+		if timeout <= 0 {
+			return nil, fmt.Errorf("invalid timeout for endpoint %q: must be positive", name)
+		}
+
+		out[name] = timeout
+	}
+
+	return out, nil
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacelift-io/vcs-agent/agent"
+	"github.com/spacelift-io/vcs-agent/privatevcs/validation"
+)
+
+func TestLoadTargetConfigs(t *testing.T) {
+	write := func(t *testing.T, content string) string {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+		return path
+	}
+
+	defaults := &targetConfig{
+		Token:                "flag-token",
+		Parallelism:          4,
+		AllowedProjects:      ".*",
+		ValidationChainMode:  "all",
+		HTTPTimeout:          25 * time.Second,
+		HTTPEndpointTimeouts: map[string]string{"Get Repository Tarball": "5m"},
+	}
+
+	t.Run("with several targets", func(t *testing.T) {
+		configs, err := loadTargetConfigs(write(t, `
+targets:
+  - name: gitlab
+    vendor: gitlab
+    target_base_endpoint: https://gitlab.example.com
+    token_file: /var/run/secrets/gitlab-token
+    http_timeout: 1m
+    http_endpoint_timeouts:
+      Get Repository Tarball: 10m
+  - name: bitbucket
+    vendor: bitbucket_datacenter
+    target_base_endpoint: https://bitbucket.example.com
+    token: bitbucket-token
+    parallelism: 2
+`), defaults)
+		require.NoError(t, err)
+		require.Len(t, configs, 2)
+
+		gitlab, bitbucket := configs[0], configs[1]
+
+		require.Equal(t, "gitlab", gitlab.Name)
+		require.Empty(t, gitlab.Token, "the token from the flags shouldn't be used")
+		require.Equal(t, "/var/run/secrets/gitlab-token", gitlab.TokenFile)
+		require.Equal(t, 4, gitlab.Parallelism)
+		require.Equal(t, time.Minute, gitlab.HTTPTimeout)
+		require.Equal(t, "10m", gitlab.HTTPEndpointTimeouts["Get Repository Tarball"])
+
+		require.Equal(t, "bitbucket-token", bitbucket.Token)
+		require.Equal(t, 2, bitbucket.Parallelism)
+		require.Equal(t, 25*time.Second, bitbucket.HTTPTimeout)
+		require.Equal(t, "5m", bitbucket.HTTPEndpointTimeouts["Get Repository Tarball"], "defaults shouldn't be shared between targets")
+
+		for _, config := range configs {
+			require.NoError(t, config.validate())
+		}
+	})
+
+	t.Run("with a duplicate name", func(t *testing.T) {
+		path := write(t, `
+targets:
+  - name: gitlab
+  - name: gitlab
+`)
+
+		_, err := loadTargetConfigs(path, defaults)
+
+		require.EqualError(t, err, `invalid config file "`+path+`": duplicate target name "gitlab"`)
+	})
+
+	t.Run("with allowed projects for GitHub Enterprise", func(t *testing.T) {
+		configs, err := loadTargetConfigs(write(t, `
+targets:
+  - name: github
+    vendor: github_enterprise
+    target_base_endpoint: https://github.example.com
+    token: github-token
+    allowed_projects: .*
+  - name: github-without-projects
+    vendor: github_enterprise
+    target_base_endpoint: https://github.example.com
+    token: github-token
+`), defaults)
+		require.NoError(t, err)
+		require.Len(t, configs, 2)
+
+		require.EqualError(t, configs[0].validate(), "allowed projects are not currently supported for the GitHub Enterprise integration", "explicitly allowing all projects should count as set")
+		require.NoError(t, configs[1].validate())
+	})
+
+	t.Run("without targets", func(t *testing.T) {
+		path := write(t, "targets: []\n")
+
+		_, err := loadTargetConfigs(path, defaults)
+
+		require.EqualError(t, err, `invalid config file "`+path+`": no targets defined`)
+	})
+}
+
+func TestParseEndpointTimeouts(t *testing.T) {
+	t.Run("with a valid timeout", func(t *testing.T) {
+		timeouts, err := parseEndpointTimeouts(validation.GitLab, nil, map[string]string{"Get Repository Tarball": "10m"})
+
+		require.NoError(t, err)
+		require.Equal(t, 10*time.Minute, timeouts["Get Repository Tarball"])
+	})
+
+	for _, value := range []string{"0s", "-1m"} {
+		t.Run("with a timeout of "+value, func(t *testing.T) {
+			_, err := parseEndpointTimeouts(validation.GitLab, nil, map[string]string{"Get Repository Tarball": value})
+
+			require.EqualError(t, err, `invalid timeout for endpoint "Get Repository Tarball": must be positive`)
+		})
+	}
+}
+
+func TestValidateBackoff(t *testing.T) {
+	valid := agent.Backoff{
+		Initial:    time.Second,
+		Max:        time.Minute,
+		Multiplier: 2,
+		Jitter:     0.5,
+		ResetAfter: time.Minute,
+	}
+
+	require.NoError(t, validateBackoff(valid))
+
+	testCases := []struct {
+		name   string
+		modify func(*agent.Backoff)
+		err    string
+	}{
+		{
+			name:   "with no initial delay",
+			modify: func(b *agent.Backoff) { b.Initial = 0 },
+			err:    "--reconnect-initial-delay must be positive",
+		},
+		{
+			name:   "with a max delay below the initial one",
+			modify: func(b *agent.Backoff) { b.Max = time.Millisecond },
+			err:    "--reconnect-max-delay must be at least --reconnect-initial-delay",
+		},
+		{
+			name:   "with a shrinking multiplier",
+			modify: func(b *agent.Backoff) { b.Multiplier = 0.5 },
+			err:    "--reconnect-multiplier must be at least 1",
+		},
+		{
+			name:   "with too much jitter",
+			modify: func(b *agent.Backoff) { b.Jitter = 2 },
+			err:    "--reconnect-jitter must be between 0 and 1",
+		},
+		{
+			name:   "with no reset delay",
+			modify: func(b *agent.Backoff) { b.ResetAfter = 0 },
+			err:    "--reconnect-reset-after must be positive",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			backoff := valid
+			testCase.modify(&backoff)
+
+			require.EqualError(t, validateBackoff(backoff), testCase.err)
+		})
+	}
+}
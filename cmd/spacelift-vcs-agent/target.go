@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spacelift-io/spcontext"
+	"github.com/urfave/cli/v3"
+
+	"github.com/spacelift-io/vcs-agent/agent"
+	"github.com/spacelift-io/vcs-agent/logging"
+	"github.com/spacelift-io/vcs-agent/privatevcs"
+	"github.com/spacelift-io/vcs-agent/privatevcs/validation"
+	"github.com/spacelift-io/vcs-agent/transport"
+)
+
+// target is a VCS target served by its own agent.
+type target struct {
+	config          *targetConfig
+	ctx             *spcontext.Context
+	agent           *agent.Agent
+	tokenFile       *tokenFile
+	validationFiles *validationFiles
+
+	// stopped is closed once the target is stopped because of an error it
+	// can't recover from.
+	stopped  chan struct{}
+	stopOnce sync.Once
+}
+
+// newTarget creates the agent serving the target. Settings shared by all
+// targets, like the gateway connection, are taken from the flags.
+func newTarget(ctx *spcontext.Context, cmd *cli.Command, config *targetConfig, metadata map[string]string) (*target, error) {
+	if config.Name != "" {
+		ctx = ctx.With("target", config.Name)
+	}
+
+	out := &target{config: config, ctx: ctx, stopped: make(chan struct{})}
+
+	var poolConfig *privatevcs.AgentPoolConfig
+	var err error
+
+	if config.TokenFile != "" {
+		out.tokenFile = &tokenFile{path: config.TokenFile}
+		poolConfig, err = out.tokenFile.load()
+	} else {
+		poolConfig, err = parsePoolToken([]byte(config.Token))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if out.validationFiles, err = loadValidationFiles(config); err != nil {
+		return nil, err
+	}
+
+	transportConfig := config.transportConfig()
+
+	targetTransport, err := transport.New(transportConfig)
+	if err != nil {
+		return nil, fmt.Errorf("could not create HTTP transport: %w", err)
+	}
+
+	if transportConfig.HasCustomCAs() {
+		ctx.Infof("using custom ca certificate")
+	}
+
+	// Redirects are followed by the agent according to its redirect policy.
+	client := &http.Client{
+		Transport: targetTransport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	var httpClient agent.RequestDoer = client
+
+	if config.DebugPrintAll {
+		httpClient = &logging.HTTPClient{
+			Wrapped: client,
+			Out:     &logging.ConcurrentSafeWriter{Out: os.Stdout},
+		}
+	}
+
+	base := config.TargetBaseEndpoint
+	if strings.HasPrefix(base, "http://") && strings.Contains(base, "gitlab") {
+		ctx.Warnf("GitLab integration might not work correctly using HTTP. Please use HTTPS.")
+	}
+
+	endpointTimeouts, err := parseEndpointTimeouts(validation.Vendor(config.Vendor), out.validationFiles.patterns(), config.HTTPEndpointTimeouts)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint timeouts: %w", err)
+	}
+
+	out.agent, err = agent.New(&agent.AgentConfig{
+		PoolConfig:                     poolConfig,
+		GatewayHosts:                   cmd.StringSlice(flagGatewayHosts.Name),
+		GatewayProxy:                   cmd.String(flagGatewayProxy.Name),
+		GatewayPins:                    cmd.StringSlice(flagGatewayPins.Name),
+		GatewayClientCertificate:       gatewayClientCertificate(cmd),
+		Target:                         config.Name,
+		TargetBaseEndpoint:             base,
+		Vendor:                         config.Vendor,
+		Validator:                      out.validationFiles.strategy,
+		ValidationMode:                 out.validationFiles.mode,
+		Version:                        VERSION,
+		Metadata:                       metadata,
+		HTTPClient:                     httpClient,
+		HTTPDisableResponseCompression: config.HTTPDisableResponseCompression,
+		HTTPResponseChunkSize:          config.HTTPResponseChunkSize,
+		HTTPTimeout:                    config.HTTPTimeout,
+		HTTPMaxTimeout:                 config.HTTPMaxTimeout,
+		HTTPEndpointTimeouts:           endpointTimeouts,
+		AllowlistPatterns:              out.validationFiles.patterns,
+		RedirectMode:                   agent.RedirectMode(config.HTTPRedirectMode),
+		RedirectAllowedHosts:           config.HTTPRedirectAllowedHosts,
+		MaxRedirects:                   config.HTTPMaxRedirects,
+		StreamConcurrency:              config.StreamConcurrency,
+		KeepaliveTime:                  cmd.Duration(flagKeepaliveTime.Name),
+		KeepaliveTimeout:               cmd.Duration(flagKeepaliveTimeout.Name),
+		PingTimeout:                    cmd.Duration(flagPingTimeout.Name),
+		DialInsecure:                   cmd.Bool(flagDialInsecure.Name),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create agent: %w", err)
+	}
+
+	return out, nil
+}
+
+func gatewayClientCertificate(cmd *cli.Command) *transport.ClientCertificate {
+	if !cmd.IsSet(flagGatewayClientCert.Name) && !cmd.IsSet(flagGatewayClientKey.Name) &&
+		!cmd.IsSet(flagGatewayClientCertPath.Name) && !cmd.IsSet(flagGatewayClientKeyPath.Name) {
+		return nil
+	}
+
+	return &transport.ClientCertificate{
+		CertBase64:  cmd.String(flagGatewayClientCert.Name),
+		KeyBase64:   cmd.String(flagGatewayClientKey.Name),
+		CertPath:    cmd.String(flagGatewayClientCertPath.Name),
+		KeyPath:     cmd.String(flagGatewayClientKeyPath.Name),
+		KeyPassword: cmd.String(flagGatewayClientKeyPassword.Name),
+	}
+}
+
+// run starts the target's streams, each reconnecting with its own copy of the
+// backoff until signalCtx is done or the target is stopped.
+func (t *target) run(signalCtx context.Context, wg *sync.WaitGroup, backoff agent.Backoff) {
+	for range t.config.Parallelism {
+		wg.Add(1)
+
+		go func(backoff agent.Backoff) {
+			defer wg.Done()
+
+			for signalCtx.Err() == nil && !t.isStopped() {
+				t.ctx.Infof("Starting new stream.")
+
+				start := time.Now()
+				err := runStream(t.ctx, t.agent, t.stop)
+				if errors.Is(err, agent.ErrPoolRotated) {
+					// The rotated stream drains in the background.
+					continue
+				}
+
+				delay := backoff.Next(time.Since(start), err)
+				if delay > 0 && !t.isStopped() {
+					t.ctx.With("delay", delay).Infof("Reconnecting after a delay.")
+				}
+
+				select {
+				case <-time.After(delay):
+				case <-signalCtx.Done():
+				case <-t.stopped:
+				}
+			}
+		}(backoff)
+	}
+}
+
+// stop stops the target after an error it can't recover from, draining its
+// streams. The other targets keep running.
+func (t *target) stop() {
+	t.stopOnce.Do(func() {
+		t.ctx.Warnf("Stopping the target, the other targets keep running.")
+		t.agent.Drain()
+		close(t.stopped)
+	})
+}
+
+// isStopped returns whether the target has been stopped.
+func (t *target) isStopped() bool {
+	select {
+	case <-t.stopped:
+		return true
+	default:
+		return false
+	}
+}
+
+// waitForTargets calls cancel once all targets have been stopped.
+func waitForTargets(ctx *spcontext.Context, targets []*target, cancel context.CancelFunc) {
+	for _, t := range targets {
+		<-t.stopped
+	}
+
+	ctx.Warnf("All targets have been stopped, shutting down.")
+	cancel()
+}
+
+// runStream runs a single agent stream, reporting any errors and panics.
+func runStream(ctx *spcontext.Context, a *agent.Agent, stop func()) (err error) {
+	defer func() {
+		// Recover error which has already been sent by bugsnag below.
+		if recovered := recover(); recovered != nil {
+			ctx.Errorf("%v", recovered)
+			err = fmt.Errorf("panic: %v", recovered)
+		}
+	}()
+
+	if ctx.Notifier != nil {
+		defer ctx.Notifier.AutoNotify(ctx)
+	}
+
+	if err = a.Run(ctx); err != nil {
+		if !errors.Is(err, agent.ErrPoolRotated) && !strings.Contains(err.Error(), "context canceled") {
+			handleErrorReport(ctx, err, stop)
+		}
+	}
+
+	return err
+}
+
+// handleErrorReport logs or reports the error of a stream, calling stop if the
+// target is misconfigured.
+func handleErrorReport(ctx *spcontext.Context, err error, stop func()) {
+	var missConfigErr *agent.MisconfigurationError
+
+	if errors.As(err, &missConfigErr) {
+		ctx.Warnf("%s", err.Error())
+		stop()
+		return
+	}
+
+	var proxyErr *agent.ProxyError
+	if errors.As(err, &proxyErr) {
+		ctx.Warnf("Couldn't tunnel through the gateway proxy, please check the proxy configuration: %s", err.Error())
+		return
+	}
+
+	if agent.IsAuthError(err) {
+		ctx.Warnf("The gateway rejected the agent's credentials, please check the pool token: %s", err.Error())
+		return
+	}
+
+	_ = ctx.RawError(err, "error running agent")
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/spacelift-io/spcontext"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacelift-io/vcs-agent/agent"
+	"github.com/spacelift-io/vcs-agent/privatevcs"
+	"github.com/spacelift-io/vcs-agent/privatevcs/validation/blocklist"
+)
+
+func TestTargetStop(t *testing.T) {
+	ctx := spcontext.New(log.NewNopLogger())
+
+	newTestTarget := func(name string) *target {
+		a, err := agent.New(&agent.AgentConfig{
+			PoolConfig:         &privatevcs.AgentPoolConfig{Host: "gateway:1983"},
+			Target:             name,
+			TargetBaseEndpoint: "https://gitlab.example.com",
+			Validator:          new(blocklist.List),
+		})
+		require.NoError(t, err, "could not create agent")
+
+		return &target{config: &targetConfig{Name: name}, ctx: ctx, agent: a, stopped: make(chan struct{})}
+	}
+
+	gitlab, bitbucket := newTestTarget("gitlab"), newTestTarget("bitbucket")
+
+	cancelled := make(chan struct{})
+	go waitForTargets(ctx, []*target{gitlab, bitbucket}, func() { close(cancelled) })
+
+	handleErrorReport(ctx, agent.NewMisconfigurationError(errors.New("invalid token")), gitlab.stop)
+	gitlab.stop()
+
+	require.True(t, gitlab.isStopped())
+	require.False(t, bitbucket.isStopped(), "the other targets should keep running")
+
+	select {
+	case <-cancelled:
+		t.Fatal("the agent shouldn't shut down while a target is running")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	bitbucket.stop()
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("the agent should shut down once all targets are stopped")
+	}
+}
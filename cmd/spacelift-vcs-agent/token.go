@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spacelift-io/spcontext"
+
+	"github.com/spacelift-io/vcs-agent/agent"
+	"github.com/spacelift-io/vcs-agent/privatevcs"
+)
+
+// parsePoolToken decodes the pool config from a base64 encoded pool token.
+func parsePoolToken(token []byte) (*privatevcs.AgentPoolConfig, error) {
+	configBytes, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(token)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid pool token: %w", err)
+	}
+
+	var out privatevcs.AgentPoolConfig
+	if err := json.Unmarshal(configBytes, &out); err != nil {
+		return nil, fmt.Errorf("invalid pool token: %w", err)
+	}
+
+	return &out, nil
+}
+
+// tokenFile is a pool token file which is reloaded when it changes, so that
+// rotated tokens are picked up without a restart.
+type tokenFile struct {
+	path  string
+	token []byte
+
+	// rejected is the last token which couldn't be rotated to and lastError
+	// the error of the last failed read, neither is logged again until the
+	// file changes.
+	rejected  []byte
+	lastError string
+}
+
+// load reads and parses the token file.
+func (f *tokenFile) load() (*privatevcs.AgentPoolConfig, error) {
+	token, err := os.ReadFile(f.path)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't read pool token file: %w", err)
+	}
+
+	config, err := parsePoolToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	f.token = token
+
+	return config, nil
+}
+
+// reload passes the pool config to the agent if the token changed. Invalid
+// tokens are logged and the current one is kept.
+func (f *tokenFile) reload(ctx *spcontext.Context, a *agent.Agent) {
+	token, err := os.ReadFile(f.path)
+	if err != nil {
+		if err.Error() != f.lastError {
+			f.lastError = err.Error()
+			ctx.With("error", err.Error()).Warnf("Couldn't reload the pool token, keeping the current one.")
+		}
+		return
+	}
+
+	f.lastError = ""
+
+	if bytes.Equal(token, f.token) || bytes.Equal(token, f.rejected) {
+		return
+	}
+
+	config, err := parsePoolToken(token)
+	if err == nil {
+		err = a.SetPoolConfig(config)
+	}
+	if err != nil {
+		f.rejected = token
+		ctx.With("error", err.Error()).Warnf("Couldn't rotate the pool token, keeping the current one.")
+		return
+	}
+
+	f.token, f.rejected = token, nil
+
+	ctx.Infof("Pool token rotated, draining streams.")
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/spacelift-io/spcontext"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacelift-io/vcs-agent/agent"
+	"github.com/spacelift-io/vcs-agent/privatevcs/validation/blocklist"
+)
+
+func TestTokenFileReload(t *testing.T) {
+	var logs bytes.Buffer
+	ctx := spcontext.New(log.NewLogfmtLogger(&logs))
+	path := filepath.Join(t.TempDir(), "token")
+
+	write := func(t *testing.T, token string) []byte {
+		require.NoError(t, os.WriteFile(path, []byte(token), 0o600))
+		return []byte(token)
+	}
+
+	warnings := func() int {
+		return strings.Count(logs.String(), "Couldn't rotate the pool token")
+	}
+
+	original := write(t, base64.StdEncoding.EncodeToString([]byte(`{"host":"gateway:1983"}`)))
+
+	f := &tokenFile{path: path}
+	poolConfig, err := f.load()
+	require.NoError(t, err)
+
+	a, err := agent.New(&agent.AgentConfig{
+		PoolConfig:         poolConfig,
+		TargetBaseEndpoint: "https://gitlab.example.com",
+		Validator:          new(blocklist.List),
+	})
+	require.NoError(t, err, "could not create agent")
+
+	t.Run("with a token without a gateway host", func(t *testing.T) {
+		write(t, base64.StdEncoding.EncodeToString([]byte(`{"key":"secret"}`)))
+
+		f.reload(ctx, a)
+		f.reload(ctx, a)
+
+		require.Equal(t, original, f.token, "the current token should be kept")
+		require.Equal(t, 1, warnings(), "the token should only be reported once")
+	})
+
+	t.Run("with another invalid token", func(t *testing.T) {
+		write(t, "not base64")
+
+		f.reload(ctx, a)
+		f.reload(ctx, a)
+
+		require.Equal(t, original, f.token, "the current token should be kept")
+		require.Equal(t, 2, warnings(), "the new token should be reported once")
+	})
+
+	t.Run("with a valid token", func(t *testing.T) {
+		rotated := write(t, base64.StdEncoding.EncodeToString([]byte(`{"host":"gateway:1984"}`)))
+
+		f.reload(ctx, a)
+
+		require.Equal(t, rotated, f.token)
+		require.Nil(t, f.rejected)
+		require.Equal(t, 2, warnings())
+	})
+}
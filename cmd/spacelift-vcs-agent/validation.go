@@ -0,0 +1,293 @@
+package main
+
+import (
+	"bytes"
+	"expvar"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spacelift-io/spcontext"
+
+	"github.com/spacelift-io/vcs-agent/privatevcs/validation"
+	"github.com/spacelift-io/vcs-agent/privatevcs/validation/allowlist"
+	"github.com/spacelift-io/vcs-agent/privatevcs/validation/blocklist"
+)
+
+// validationReloads holds the reload status of the validation files of each
+// target, exposed through expvar so that failed reloads can be alerted on.
+var validationReloads = expvar.NewMap("vcs_agent_validation_reloads")
+
+// validationStrategy builds the validation strategy of the target and returns
+// it along with the validation mode advertised to the gateway. Several
+// strategies are combined into a chain.
+func (c *targetConfig) validationStrategy() (validation.Strategy, string, error) {
+	var (
+		strategies []validation.Strategy
+		modes      []string
+		patterns   *allowlist.Patterns
+	)
+
+	// The user-defined patterns are shared by the allowlist and the blocklist,
+	// so that both match requests with the same endpoints.
+	if c.UseAllowlist && c.AllowlistPath != "" {
+		var err error
+		if patterns, err = allowlist.LoadPatterns(c.AllowlistPath, validation.Vendor(c.Vendor)); err != nil {
+			return nil, "", fmt.Errorf("could not create request allowlist: %w", err)
+		}
+	}
+
+	for _, mode := range c.validationOrder() {
+		var strategy validation.Strategy
+		var err error
+
+		switch {
+		case mode == validationModeAllowlist && c.UseAllowlist:
+			if strategy, err = allowlist.NewWithPatterns(c.AllowedProjects, patterns); err != nil {
+				return nil, "", fmt.Errorf("could not create request allowlist: %w", err)
+			}
+		case mode == validationModeBlocklist && c.BlocklistPath != "":
+			if strategy, err = blocklist.LoadWithPatterns(c.BlocklistPath, patterns); err != nil {
+				return nil, "", fmt.Errorf("could not create request blocklist: %w", err)
+			}
+		default:
+			continue
+		}
+
+		strategies = append(strategies, strategy)
+		modes = append(modes, mode)
+	}
+
+	switch len(strategies) {
+	case 0:
+		return new(blocklist.List), validationModeNone, nil
+	case 1:
+		return strategies[0], modes[0], nil
+	}
+
+	chain, err := validation.NewChain(validation.ChainMode(c.ValidationChainMode), strategies...)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return chain, strings.Join(modes, ","), nil
+}
+
+// validationOrder returns the order in which the validation strategies are
+// evaluated.
+func (c *targetConfig) validationOrder() []string {
+	if len(c.ValidationOrder) == 0 {
+		return []string{validationModeAllowlist, validationModeBlocklist}
+	}
+
+	return c.ValidationOrder
+}
+
+// validationPaths returns the files the validation strategy is loaded from.
+func (c *targetConfig) validationPaths() []string {
+	var out []string
+
+	if c.UseAllowlist && c.AllowlistPath != "" {
+		out = append(out, c.AllowlistPath)
+	}
+
+	if c.BlocklistPath != "" {
+		out = append(out, c.BlocklistPath)
+	}
+
+	return out
+}
+
+// validationFiles are the files the validation strategy of a target is loaded
+// from. The strategy is rebuilt when they change, so that rules can be updated
+// without a restart.
+type validationFiles struct {
+	config   *targetConfig
+	strategy *validation.Reloadable
+	mode     string
+	contents [][]byte
+	status   *expvar.Map
+
+	// lastError is the error of the last failed reload, which isn't logged
+	// again until the files change.
+	lastError string
+}
+
+// loadValidationFiles builds the validation strategy of the target.
+func loadValidationFiles(config *targetConfig) (*validationFiles, error) {
+	// The files are read before the strategy is built from them, so that
+	// changes made in between are picked up by the next reload.
+	contents, err := readValidationFiles(config)
+	if err != nil {
+		return nil, err
+	}
+
+	strategy, mode, err := config.validationStrategy()
+	if err != nil {
+		return nil, err
+	}
+
+	out := &validationFiles{
+		config:   config,
+		strategy: validation.NewReloadable(strategy),
+		mode:     mode,
+		contents: contents,
+		status:   new(expvar.Map).Init(),
+	}
+
+	if out.watched() {
+		name := config.Name
+		if name == "" {
+			name = "default"
+		}
+
+		out.setStatus(nil)
+		validationReloads.Set(name, out.status)
+	}
+
+	return out, nil
+}
+
+// watched returns whether the strategy is loaded from any files.
+func (f *validationFiles) watched() bool {
+	return len(f.config.validationPaths()) > 0
+}
+
+// patterns returns the user-defined allowlist patterns of the current
+// strategy, if any.
+func (f *validationFiles) patterns() *allowlist.Patterns {
+	if list := allowlistOf(f.strategy.Current()); list != nil {
+		return list.Patterns()
+	}
+
+	return nil
+}
+
+func readValidationFiles(config *targetConfig) ([][]byte, error) {
+	var out [][]byte
+
+	for _, path := range config.validationPaths() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read validation file: %w", err)
+		}
+		out = append(out, data)
+	}
+
+	return out, nil
+}
+
+// reload rebuilds the validation strategy if any of its files changed. If the
+// new strategy is invalid, the error is logged and the current one is kept.
+func (f *validationFiles) reload(ctx *spcontext.Context) {
+	contents, err := readValidationFiles(f.config)
+	if err == nil && slices.EqualFunc(contents, f.contents, bytes.Equal) {
+		return
+	}
+
+	var strategy validation.Strategy
+	if err != nil {
+		// Restoring the previous contents has to trigger a reload.
+		f.contents = nil
+	} else {
+		f.contents = contents
+		strategy, _, err = f.config.validationStrategy()
+	}
+
+	if err != nil {
+		if err.Error() != f.lastError {
+			f.lastError = err.Error()
+			f.setStatus(err)
+			ctx.With("error", err.Error()).Warnf("Couldn't reload the validation rules, keeping the current ones.")
+		}
+		return
+	}
+
+	f.lastError = ""
+	previous := f.strategy.Swap(strategy)
+	f.setStatus(nil)
+
+	ctx = ctx.With(strategyDiff(previous, strategy)...)
+	ctx.Infof("Validation rules reloaded.")
+}
+
+// setStatus records the outcome of the last load of the files.
+func (f *validationFiles) setStatus(err error) {
+	status, lastError := new(expvar.String), new(expvar.String)
+
+	if err != nil {
+		status.Set("failed")
+		lastError.Set(err.Error())
+		f.status.Add("failures", 1)
+	} else {
+		status.Set("ok")
+		f.status.Add("successes", 1)
+	}
+
+	f.status.Set("status", status)
+	f.status.Set("last_error", lastError)
+
+	timestamp := new(expvar.String)
+	timestamp.Set(time.Now().UTC().Format(time.RFC3339))
+	f.status.Set("last_reload", timestamp)
+}
+
+// strategyDiff returns the log fields describing the rules changed between
+// the strategies.
+func strategyDiff(previous, next validation.Strategy) []any {
+	var out []any
+
+	if previousList, nextList := blocklistOf(previous), blocklistOf(next); previousList != nil && nextList != nil {
+		added, removed, changed := blocklist.Diff(previousList, nextList)
+
+		out = append(out,
+			"rules_added", strings.Join(added, ", "),
+			"rules_removed", strings.Join(removed, ", "),
+			"rules_changed", strings.Join(changed, ", "),
+		)
+	}
+
+	if previousList, nextList := allowlistOf(previous), allowlistOf(next); previousList != nil && nextList != nil {
+		added, removed, changed := allowlist.DiffPatterns(previousList.Patterns(), nextList.Patterns())
+
+		out = append(out,
+			"patterns_added", strings.Join(added, ", "),
+			"patterns_removed", strings.Join(removed, ", "),
+			"patterns_changed", strings.Join(changed, ", "),
+		)
+	}
+
+	return out
+}
+
+// blocklistOf returns the blocklist of the strategy, which may be part of a
+// chain.
+func blocklistOf(strategy validation.Strategy) *blocklist.List {
+	if chain, ok := strategy.(*validation.Chain); ok {
+		for _, strategy := range chain.Strategies {
+			if list := blocklistOf(strategy); list != nil {
+				return list
+			}
+		}
+	}
+
+	list, _ := strategy.(*blocklist.List)
+	return list
+}
+
+// allowlistOf returns the allowlist of the strategy, which may be part of a
+// chain.
+func allowlistOf(strategy validation.Strategy) *allowlist.List {
+	if chain, ok := strategy.(*validation.Chain); ok {
+		for _, strategy := range chain.Strategies {
+			if list := allowlistOf(strategy); list != nil {
+				return list
+			}
+		}
+	}
+
+	list, _ := strategy.(*allowlist.List)
+	return list
+}
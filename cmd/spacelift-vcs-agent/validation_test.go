@@ -0,0 +1,180 @@
+package main
+
+import (
+	"expvar"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/spacelift-io/spcontext"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacelift-io/vcs-agent/privatevcs/validation"
+	"github.com/spacelift-io/vcs-agent/privatevcs/validation/allowlist"
+	"github.com/spacelift-io/vcs-agent/privatevcs/validation/blocklist"
+)
+
+func TestValidationFilesReload(t *testing.T) {
+	ctx := spcontext.New(log.NewNopLogger())
+	path := filepath.Join(t.TempDir(), "blocklist.yaml")
+
+	write := func(t *testing.T, content string) {
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	}
+
+	ruleNames := func(f *validationFiles) []string {
+		var out []string
+		for _, rule := range f.strategy.Current().(*blocklist.List).Rules {
+			out = append(out, rule.Name)
+		}
+		return out
+	}
+
+	status := func(f *validationFiles) string {
+		return f.status.Get("status").(*expvar.String).Value()
+	}
+
+	write(t, `
+rules:
+  - name: No deletes
+    method: DELETE
+    path: .*
+`)
+
+	f, err := loadValidationFiles(&targetConfig{Name: "reload-test", BlocklistPath: path})
+	require.NoError(t, err)
+	require.True(t, f.watched())
+	require.Equal(t, validationModeBlocklist, f.mode)
+	require.Equal(t, []string{"No deletes"}, ruleNames(f))
+	require.Equal(t, "ok", status(f))
+
+	t.Run("with valid rules", func(t *testing.T) {
+		write(t, `
+rules:
+  - name: No pushes
+    method: POST
+    path: .*
+`)
+
+		f.reload(ctx)
+
+		require.Equal(t, []string{"No pushes"}, ruleNames(f))
+		require.Equal(t, "ok", status(f))
+	})
+
+	t.Run("with invalid rules", func(t *testing.T) {
+		write(t, `
+rules:
+  - name: Invalid
+    method: GET
+    path: "["
+`)
+
+		f.reload(ctx)
+
+		require.Equal(t, []string{"No pushes"}, ruleNames(f), "the previous rules should be kept")
+		require.Equal(t, "failed", status(f))
+		require.Contains(t, f.status.Get("last_error").String(), "invalid path matcher")
+		require.Equal(t, "1", f.status.Get("failures").String())
+	})
+
+	t.Run("with the file removed", func(t *testing.T) {
+		require.NoError(t, os.Remove(path))
+
+		f.reload(ctx)
+
+		require.Equal(t, []string{"No pushes"}, ruleNames(f), "the previous rules should be kept")
+		require.Equal(t, "failed", status(f))
+	})
+
+	t.Run("with the file restored", func(t *testing.T) {
+		write(t, `
+rules:
+  - name: No pushes
+    method: POST
+    path: .*
+`)
+
+		f.reload(ctx)
+
+		require.Equal(t, "ok", status(f))
+	})
+}
+
+func TestValidationFilesWithoutFiles(t *testing.T) {
+	f, err := loadValidationFiles(&targetConfig{UseAllowlist: true, AllowedProjects: ".*"})
+	require.NoError(t, err)
+
+	require.False(t, f.watched())
+	require.Equal(t, validationModeAllowlist, f.mode)
+}
+
+func TestValidationStrategy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("rules: []\n"), 0o600))
+
+	t.Run("with the allowlist and the blocklist", func(t *testing.T) {
+		config := &targetConfig{UseAllowlist: true, AllowedProjects: ".*", BlocklistPath: path, ValidationChainMode: "first"}
+		require.NoError(t, config.validateValidationOrder())
+
+		strategy, mode, err := config.validationStrategy()
+		require.NoError(t, err)
+
+		chain, ok := strategy.(*validation.Chain)
+		require.True(t, ok, "the strategies should be chained")
+		require.Equal(t, validation.ChainModeFirst, chain.Mode)
+		require.IsType(t, &allowlist.List{}, chain.Strategies[0])
+		require.IsType(t, &blocklist.List{}, chain.Strategies[1])
+		require.Equal(t, "allowlist,blocklist", mode)
+
+		f, err := loadValidationFiles(config)
+		require.NoError(t, err)
+		require.True(t, f.watched(), "the blocklist should be reloaded")
+	})
+
+	t.Run("with a custom order", func(t *testing.T) {
+		config := &targetConfig{UseAllowlist: true, AllowedProjects: ".*", BlocklistPath: path, ValidationOrder: []string{"blocklist", "allowlist"}, ValidationChainMode: "all"}
+
+		_, mode, err := config.validationStrategy()
+		require.NoError(t, err)
+		require.Equal(t, "blocklist,allowlist", mode)
+	})
+
+	t.Run("with an allowlist file", func(t *testing.T) {
+		allowlistPath := filepath.Join(t.TempDir(), "allowlist.yaml")
+		require.NoError(t, os.WriteFile(allowlistPath, []byte(`
+patterns:
+  - name: Get Repository Languages
+    method: GET
+    path: ^/api/v4/projects/(?P<project>[^/]+)/languages$
+`), 0o600))
+
+		config := &targetConfig{Vendor: "gitlab", UseAllowlist: true, AllowedProjects: ".*", AllowlistPath: allowlistPath}
+
+		f, err := loadValidationFiles(config)
+		require.NoError(t, err)
+		require.True(t, f.watched(), "the allowlist file should be reloaded")
+		require.Len(t, f.strategy.Current().(*allowlist.List).Patterns().Patterns, 1)
+
+		timeouts, err := parseEndpointTimeouts(validation.GitLab, f.patterns(), map[string]string{"Get Repository Languages": "1m"})
+		require.NoError(t, err, "user-defined endpoints should be known")
+		require.Equal(t, time.Minute, timeouts["Get Repository Languages"])
+
+		require.EqualError(t, (&targetConfig{Vendor: "gitlab", TargetBaseEndpoint: "https://gitlab.example.com", Token: "token", Parallelism: 1, AllowedProjects: ".*", AllowlistPath: allowlistPath}).validate(),
+			"the allowlist file requires the allowlist to be enabled")
+	})
+
+	t.Run("with an enabled strategy missing from the order", func(t *testing.T) {
+		config := &targetConfig{BlocklistPath: path, ValidationOrder: []string{"allowlist"}}
+
+		require.EqualError(t, config.validateValidationOrder(), "the blocklist is enabled but missing from the validation order")
+	})
+
+	t.Run("with an unknown strategy in the order", func(t *testing.T) {
+		config := &targetConfig{ValidationOrder: []string{"denylist"}}
+
+		require.EqualError(t, config.validateValidationOrder(), `invalid validation order entry "denylist", use "allowlist" or "blocklist"`)
+	})
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	"github.com/spacelift-io/spcontext"
+)
+
+// watchFiles reloads the pool token files of the targets every tokenInterval
+// and their validation files every validationInterval, as well as both
+// whenever reload receives, until the context is done.
+func watchFiles(ctx *spcontext.Context, targets []*target, tokenInterval, validationInterval time.Duration, reload <-chan os.Signal) {
+	var tokenFiles, validationFiles bool
+	for _, t := range targets {
+		tokenFiles = tokenFiles || t.tokenFile != nil
+		validationFiles = validationFiles || t.validationFiles.watched()
+	}
+
+	var tokenTicks, validationTicks <-chan time.Time
+
+	if tokenFiles {
+		ticker := time.NewTicker(tokenInterval)
+		defer ticker.Stop()
+		tokenTicks = ticker.C
+	}
+
+	if validationFiles && validationInterval > 0 {
+		ticker := time.NewTicker(validationInterval)
+		defer ticker.Stop()
+		validationTicks = ticker.C
+	}
+
+	reloadTokenFiles := func() {
+		for _, t := range targets {
+			if t.tokenFile != nil {
+				t.tokenFile.reload(t.ctx, t.agent)
+			}
+		}
+	}
+
+	reloadValidationFiles := func() {
+		for _, t := range targets {
+			if t.validationFiles.watched() {
+				t.validationFiles.reload(t.ctx)
+			}
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tokenTicks:
+			reloadTokenFiles()
+		case <-validationTicks:
+			reloadValidationFiles()
+		case <-reload:
+			// Reload signals are received even without any files, so that
+			// they don't shut down the agent.
+			if !tokenFiles && !validationFiles {
+				ctx.Warnf("SIGHUP received, but neither the pool token nor the validation rules are loaded from a file, nothing to reload.")
+				continue
+			}
+
+			ctx.Infof("Reloading files.")
+			reloadTokenFiles()
+			reloadValidationFiles()
+		}
+	}
+}
@@ -1,5 +1,7 @@
 package privatevcs
 
+import "slices"
+
 // MetadataFieldVcsAgentPoolULID is the gRPC metadata header where the agent sends its pool ulid.
 const MetadataFieldVcsAgentPoolULID = "vcs-agent-pool-ulid"
 
@@ -9,9 +11,68 @@ const MetadataFieldVCSAgentKey = "vcs-agent-key"
 // MetadataFieldVCSAgentMetadata is the gRPC metadata header where the agent sends its metadata.
 const MetadataFieldVCSAgentMetadata = "vcs-agent-metadata"
 
+// MetadataFieldVCSAgentStreamConcurrency is the gRPC metadata header where the agent sends the
+// maximum number of requests it handles concurrently on a single stream.
+const MetadataFieldVCSAgentStreamConcurrency = "vcs-agent-stream-concurrency"
+
+// MetadataFieldVCSAgentVersion is the gRPC metadata header where the agent sends its version.
+const MetadataFieldVCSAgentVersion = "vcs-agent-version"
+
+// MetadataFieldVCSAgentCapabilities is the gRPC metadata header where the agent sends the
+// comma-separated list of protocol capabilities it supports.
+const MetadataFieldVCSAgentCapabilities = "vcs-agent-capabilities"
+
+// MetadataFieldVCSAgentVendor is the gRPC metadata header where the agent sends the VCS vendor it proxies.
+const MetadataFieldVCSAgentVendor = "vcs-agent-vendor"
+
+// MetadataFieldVCSAgentValidationMode is the gRPC metadata header where the agent sends the
+// request validation mode it enforces.
+const MetadataFieldVCSAgentValidationMode = "vcs-agent-validation-mode"
+
+// Protocol capabilities advertised by agents, allowing gateways to only use the
+// features the agent they're talking to supports.
+const (
+	// CapabilityChunkedResponses means the agent honors HTTPRequest.allowChunkedResponse.
+	CapabilityChunkedResponses = "chunked-responses"
+
+	// CapabilityMultiValueHeaders means the agent reads and writes multiValueHeaders.
+	CapabilityMultiValueHeaders = "multi-value-headers"
+
+	// CapabilityMultiplexing means the agent handles requests concurrently on a
+	// stream, see MetadataFieldVCSAgentStreamConcurrency.
+	CapabilityMultiplexing = "multiplexing"
+
+	// CapabilityRequestTimeouts means the agent honors HTTPRequest.timeoutMillis.
+	CapabilityRequestTimeouts = "request-timeouts"
+
+	// CapabilityCancellation means the agent handles CancelRequest messages.
+	CapabilityCancellation = "cancellation"
+
+	// CapabilityErrorDetails means the agent sets Response.errorDetails.
+	CapabilityErrorDetails = "error-details"
+
+	// CapabilityGoingAway means the agent sends GoingAway before shutting down.
+	CapabilityGoingAway = "going-away"
+)
+
 // AgentPoolConfig is the configuration which an Agent uses to find a Gateway and authenticate as an Agent Pool.
 type AgentPoolConfig struct {
-	Host     string `json:"host"`
-	Key      string `json:"key"`
-	PoolULID string `json:"pool_ulid"`
+	Host     string   `json:"host"`
+	Hosts    []string `json:"hosts,omitempty"`
+	Key      string   `json:"key"`
+	PoolULID string   `json:"pool_ulid"`
+}
+
+// GatewayHosts returns the addresses of all gateways in order of preference.
+// Tokens issued before multiple gateways were supported only contain Host.
+func (c *AgentPoolConfig) GatewayHosts() []string {
+	var out []string
+
+	for _, host := range slices.Concat(c.Hosts, []string{c.Host}) {
+		if host != "" && !slices.Contains(out, host) {
+			out = append(out, host)
+		}
+	}
+
+	return out
 }
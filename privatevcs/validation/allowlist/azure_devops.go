@@ -74,30 +74,14 @@ var azureDevOpsPatterns = map[string]azureDevOpsPattern{
 	},
 }
 
-func matchAzureDevOpsRequest(r *http.Request) (string, string, error) {
+func matchAzureDevOpsRequest(r *http.Request, overridden map[string]bool) (string, string, error) {
 	for name, pattern := range azureDevOpsPatterns {
-		if r.Method != pattern.Method {
+		if r.Method != pattern.Method || overridden[name] {
 			continue
 		}
 
 		if matches := pattern.Path.FindStringSubmatch(r.URL.EscapedPath()); matches != nil {
-			var organization, project, repositoryID string
-			if index := pattern.Path.SubexpIndex("organization"); index != -1 {
-				organization = matches[index]
-			}
-			if index := pattern.Path.SubexpIndex("project"); index != -1 {
-				project = matches[index]
-			}
-			if index := pattern.Path.SubexpIndex("repositoryId"); index != -1 {
-				repositoryID = matches[index]
-			}
-
-			var projectName string
-			if organization != "" && project != "" && repositoryID != "" {
-				projectName = fmt.Sprintf("%s/%s/%s", organization, project, repositoryID)
-			}
-
-			return name, projectName, nil
+			return name, azureDevOpsProject(pattern.Path, matches), nil
 		}
 	}
 
@@ -105,3 +89,25 @@ func matchAzureDevOpsRequest(r *http.Request) (string, string, error) {
 	// a stable set of endpoints in the validation rules
 	return "Unknown Request", "", nil
 }
+
+// azureDevOpsProject returns the project built from the "organization",
+// "project" and "repositoryId" groups.
+func azureDevOpsProject(path *regexp.Regexp, matches []string) string {
+	var organization, project, repositoryID string
+	if index := path.SubexpIndex("organization"); index != -1 {
+		organization = matches[index]
+	}
+	if index := path.SubexpIndex("project"); index != -1 {
+		project = matches[index]
+	}
+	if index := path.SubexpIndex("repositoryId"); index != -1 {
+		repositoryID = matches[index]
+	}
+
+	var projectName string
+	if organization != "" && project != "" && repositoryID != "" {
+		projectName = fmt.Sprintf("%s/%s/%s", organization, project, repositoryID)
+	}
+
+	return projectName
+}
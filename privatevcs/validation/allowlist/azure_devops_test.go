@@ -143,7 +143,7 @@ func TestAzureDevOpsValidation(t *testing.T) {
 		request, err := http.NewRequest(testCase.method, "https://github.myorg.com"+testCase.path, nil)
 		require.NoError(t, err, "could not create request")
 
-		name, project, err := matchAzureDevOpsRequest(request)
+		name, project, err := matchAzureDevOpsRequest(request, nil)
 
 		if testCase.matches {
 			require.NoError(t, err, "could not find match for %q (%s)", testCase.name, testCase.path)
@@ -86,27 +86,33 @@ var bitbucketDatacenterPatterns = map[string]bitbucketDatacenterPattern{
 	},
 }
 
-func matchBitbucketDatacenterRequest(r *http.Request) (string, string, error) {
+func matchBitbucketDatacenterRequest(r *http.Request, overridden map[string]bool) (string, string, error) {
 	for name, pattern := range bitbucketDatacenterPatterns {
-		if r.Method != pattern.Method {
+		if r.Method != pattern.Method || overridden[name] {
 			continue
 		}
 
 		if matches := pattern.Path.FindStringSubmatch(r.URL.EscapedPath()); matches != nil {
-			var project string
-			if index := pattern.Path.SubexpIndex("projectKey"); index != -1 {
-				project = matches[index]
-			}
-			var repository string
-			if index := pattern.Path.SubexpIndex("repositorySlug"); index != -1 {
-				repository = matches[index]
-			}
-			var outProject string
-			if repository != "" {
-				outProject = fmt.Sprintf("%s/%s", project, repository)
-			}
-			return name, outProject, nil
+			return name, bitbucketDatacenterProject(pattern.Path, matches), nil
 		}
 	}
 	return "", "", ErrNoMatch
 }
+
+// bitbucketDatacenterProject returns the project built from the "projectKey"
+// and "repositorySlug" groups.
+func bitbucketDatacenterProject(path *regexp.Regexp, matches []string) string {
+	var project string
+	if index := path.SubexpIndex("projectKey"); index != -1 {
+		project = matches[index]
+	}
+	var repository string
+	if index := path.SubexpIndex("repositorySlug"); index != -1 {
+		repository = matches[index]
+	}
+	var outProject string
+	if repository != "" {
+		outProject = fmt.Sprintf("%s/%s", project, repository)
+	}
+	return outProject
+}
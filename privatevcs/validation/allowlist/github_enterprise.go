@@ -79,20 +79,23 @@ var githubEnterprisePatterns = map[string]githubEnterprisePattern{
 	},
 }
 
-func matchGitHubEnterpriseRequest(r *http.Request) (string, string, error) {
+func matchGitHubEnterpriseRequest(r *http.Request, overridden map[string]bool) (string, string, error) {
 	for name, pattern := range githubEnterprisePatterns {
-		if r.Method != pattern.Method {
+		if r.Method != pattern.Method || overridden[name] {
 			continue
 		}
 
 		if matches := pattern.Path.FindStringSubmatch(r.URL.EscapedPath()); matches != nil {
-			var project string
-			if index := pattern.Path.SubexpIndex("project"); index != -1 {
-				project = matches[index]
-			}
-
-			return name, project, nil
+			return name, githubEnterpriseProject(pattern.Path, matches), nil
 		}
 	}
 	return "", "", ErrNoMatch
 }
+
+// githubEnterpriseProject returns the project captured by the "project" group.
+func githubEnterpriseProject(path *regexp.Regexp, matches []string) string {
+	if index := path.SubexpIndex("project"); index != -1 {
+		return matches[index]
+	}
+	return ""
+}
@@ -233,7 +233,7 @@ func TestGitHubEnterpriseValidation(t *testing.T) {
 		request, err := http.NewRequest(testCase.method, "https://github.myorg.com"+testCase.path, nil)
 		require.NoError(t, err, "could not create request")
 
-		name, project, err := matchGitHubEnterpriseRequest(request)
+		name, project, err := matchGitHubEnterpriseRequest(request, nil)
 
 		if testCase.matches {
 			require.NoError(t, err, "could not find match for %q", testCase.name)
@@ -114,19 +114,23 @@ var gitlabPatterns = map[string]gitlabPattern{
 	},
 }
 
-func matchGitLabRequest(r *http.Request) (string, string, error) {
+func matchGitLabRequest(r *http.Request, overridden map[string]bool) (string, string, error) {
 	for name, pattern := range gitlabPatterns {
-		if r.Method != pattern.Method {
+		if r.Method != pattern.Method || overridden[name] {
 			continue
 		}
 
 		if matches := pattern.Path.FindStringSubmatch(r.URL.EscapedPath()); matches != nil {
-			var project string
-			if index := pattern.Path.SubexpIndex("project"); index != -1 {
-				project = matches[index]
-			}
-			return name, project, nil
+			return name, gitlabProject(pattern.Path, matches), nil
 		}
 	}
 	return "", "", ErrNoMatch
 }
+
+// gitlabProject returns the project captured by the "project" group.
+func gitlabProject(path *regexp.Regexp, matches []string) string {
+	if index := path.SubexpIndex("project"); index != -1 {
+		return matches[index]
+	}
+	return ""
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/spacelift-io/spcontext"
 
+	"github.com/spacelift-io/vcs-agent/privatevcs"
 	"github.com/spacelift-io/vcs-agent/privatevcs/validation"
 )
 
@@ -16,24 +17,55 @@ import (
 // code-based allowlist (the legacy approach).
 type List struct {
 	projectRegexp *regexp.Regexp
+	patterns      *Patterns
 }
 
 // New creates a new allowlist strategy from a project regexp.
 func New(projectRegexp string) (*List, error) {
+	return NewWithPatterns(projectRegexp, nil)
+}
+
+// NewWithPatterns creates a new allowlist strategy from a project regexp,
+// allowing the user-defined API usages besides the built-in ones.
+func NewWithPatterns(projectRegexp string, patterns *Patterns) (*List, error) {
 	r, err := regexp.Compile(projectRegexp)
 	if err != nil {
 		return nil, errors.Wrapf(err, "couldn't compile project regexp %q", projectRegexp)
 	}
 
-	return &List{projectRegexp: r}, nil
+	return &List{projectRegexp: r, patterns: patterns}, nil
+}
+
+// Patterns returns the user-defined API usages of the allowlist, if any.
+func (l List) Patterns() *Patterns {
+	return l.patterns
 }
 
 // Validate validates the request and returns an error if the request should be
 // blocked.
 func (l List) Validate(ctx *spcontext.Context, vendor validation.Vendor, req *http.Request) (*spcontext.Context, error) {
-	name, project, err := MatchRequest(vendor, req)
-	if err != nil {
-		return ctx.With("match_error", err), ctx.RawError(err, "invalid request")
+	ctx, decided, err := l.Decide(ctx, vendor, req)
+	if !decided {
+		return ctx, &validation.Error{
+			Code: privatevcs.ErrorCode_ERROR_CODE_NO_ALLOWLIST_MATCH,
+			Err:  ctx.RawError(ErrNoMatch, "invalid request"),
+		}
+	}
+
+	return ctx, err
+}
+
+// Decide validates the request like Validate. It only decides on requests to
+// known API usages, leaving the others to the next strategy of a chain.
+func (l List) Decide(ctx *spcontext.Context, vendor validation.Vendor, req *http.Request) (*spcontext.Context, bool, error) {
+	name, project, err := l.patterns.MatchRequest(vendor, req)
+	if errors.Is(err, ErrNoMatch) {
+		return ctx.With("match_error", err), false, nil
+	} else if err != nil {
+		return ctx.With("match_error", err), true, &validation.Error{
+			Code: privatevcs.ErrorCode_ERROR_CODE_INVALID_REQUEST,
+			Err:  ctx.RawError(err, "invalid request"),
+		}
 	}
 
 	ctx = ctx.With("name", name)
@@ -44,7 +76,12 @@ func (l List) Validate(ctx *spcontext.Context, vendor validation.Vendor, req *ht
 			"match_error", err,
 			"project_urlencoded", project,
 		)
-		return ctx, ctx.RawError(err, "couldn't url-unescape project name")
+		return ctx, true, &validation.Error{
+			Code:     privatevcs.ErrorCode_ERROR_CODE_INVALID_REQUEST,
+			Endpoint: name,
+			Project:  project,
+			Err:      ctx.RawError(err, "couldn't url-unescape project name"),
+		}
 	}
 
 	if project != "" && !l.projectRegexp.MatchString(projectUnescaped) {
@@ -54,8 +91,13 @@ func (l List) Validate(ctx *spcontext.Context, vendor validation.Vendor, req *ht
 			"project_regexp", l.projectRegexp.String(),
 		)
 
-		return ctx, ctx.RawError(fmt.Errorf("request project didn't match allowed projects regexp"), "invalid request")
+		return ctx, true, &validation.Error{
+			Code:     privatevcs.ErrorCode_ERROR_CODE_PROJECT_NOT_ALLOWED,
+			Endpoint: name,
+			Project:  projectUnescaped,
+			Err:      ctx.RawError(fmt.Errorf("request project didn't match allowed projects regexp"), "invalid request"),
+		}
 	}
 
-	return ctx.With("project", project), nil
+	return ctx.With("project", project), true, nil
 }
@@ -3,6 +3,8 @@ package allowlist
 import (
 	"fmt"
 	"net/http"
+	"regexp"
+	"sort"
 
 	"github.com/spacelift-io/vcs-agent/privatevcs/validation"
 )
@@ -10,16 +12,61 @@ import (
 // ErrNoMatch is returned when the request didn't match any planned API usage.
 var ErrNoMatch = fmt.Errorf("vcs-agent: no match for request")
 
-var vendorMatchers = map[validation.Vendor]func(r *http.Request) (name string, project string, err error){
+// vendorMatchers match the request against the built-in patterns of each
+// vendor, skipping the overridden ones.
+var vendorMatchers = map[validation.Vendor]func(r *http.Request, overridden map[string]bool) (name string, project string, err error){
 	validation.AzureDevOps:         matchAzureDevOpsRequest,
 	validation.BitbucketDatacenter: matchBitbucketDatacenterRequest,
 	validation.GitHubEnterprise:    matchGitHubEnterpriseRequest,
 	validation.GitLab:              matchGitLabRequest,
 }
 
+// vendorProjects extract the project from the groups captured by a pattern of
+// each vendor.
+var vendorProjects = map[validation.Vendor]func(path *regexp.Regexp, matches []string) string{
+	validation.AzureDevOps:         azureDevOpsProject,
+	validation.BitbucketDatacenter: bitbucketDatacenterProject,
+	validation.GitHubEnterprise:    githubEnterpriseProject,
+	validation.GitLab:              gitlabProject,
+}
+
 // MatchRequest matches the request based on the VCS vendor.
 // It returns the API usage human-friendly name, as well as the target project.
 // If the request isn't scope to a project (i.e. listing projects) it returns an empty string.
 func MatchRequest(vendor validation.Vendor, r *http.Request) (name string, project string, err error) {
-	return vendorMatchers[vendor](r)
+	matcher, ok := vendorMatchers[vendor]
+	if !ok {
+		return "", "", ErrNoMatch
+	}
+
+	return matcher(r, nil)
+}
+
+// EndpointNames returns the sorted names of all API usages known for the
+// vendor, as returned by MatchRequest.
+func EndpointNames(vendor validation.Vendor) []string {
+	var names []string
+
+	switch vendor {
+	case validation.AzureDevOps:
+		for name := range azureDevOpsPatterns {
+			names = append(names, name)
+		}
+	case validation.BitbucketDatacenter:
+		for name := range bitbucketDatacenterPatterns {
+			names = append(names, name)
+		}
+	case validation.GitHubEnterprise:
+		for name := range githubEnterprisePatterns {
+			names = append(names, name)
+		}
+	case validation.GitLab:
+		for name := range gitlabPatterns {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	return names
 }
@@ -0,0 +1,228 @@
+package allowlist
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/spacelift-io/vcs-agent/privatevcs/validation"
+)
+
+// Pattern is a user-defined API usage allowed by the allowlist.
+type Pattern struct {
+	// The name of the API usage. A pattern named like a built-in one
+	// overrides it.
+	Name string `json:"name"`
+
+	// The HTTP method of the API usage.
+	Method string `json:"method"`
+
+	// The regular expression to match the escaped path against. The project
+	// is captured by groups named like in the built-in patterns of the
+	// vendor: "project" for GitHub Enterprise and GitLab, "projectKey" and
+	// "repositorySlug" for Bitbucket Datacenter, and "organization",
+	// "project" and "repositoryId" for Azure DevOps.
+	Path string `json:"path"`
+
+	// Whether the API usage isn't scoped to a project, in which case the
+	// path doesn't capture one and the allowed projects aren't checked.
+	NoProject bool `json:"no_project" yaml:"no_project"`
+
+	pathRegexp *regexp.Regexp
+}
+
+// vendorProjectGroups are the groups capturing the project in the patterns of
+// each vendor.
+var vendorProjectGroups = map[validation.Vendor][]string{
+	validation.AzureDevOps:         {"organization", "project", "repositoryId"},
+	validation.BitbucketDatacenter: {"projectKey", "repositorySlug"},
+	validation.GitHubEnterprise:    {"project"},
+	validation.GitLab:              {"project"},
+}
+
+// Validate compiles and validates the pattern for the vendor. Unless the
+// pattern opts out, its path has to capture the project, as requests without
+// one aren't checked against the allowed projects.
+func (p *Pattern) Validate(vendor validation.Vendor) error {
+	if p.Name == "" {
+		return errors.New("pattern name is required")
+	}
+
+	if p.Method == "" {
+		return errors.Errorf("method of pattern %q is required", p.Name)
+	}
+
+	var err error
+	if p.pathRegexp, err = regexp.Compile(p.Path); err != nil {
+		return errors.Wrapf(err, "could not compile pattern %q: invalid path matcher", p.Name)
+	}
+
+	groups, ok := vendorProjectGroups[vendor]
+	if !ok {
+		return errors.Errorf("unsupported vendor %q", vendor)
+	}
+
+	if p.NoProject {
+		return nil
+	}
+
+	for _, group := range groups {
+		if p.pathRegexp.SubexpIndex(group) == -1 {
+			return errors.Errorf("path of pattern %q doesn't capture the %q group of the project, set no_project if it isn't scoped to one", p.Name, group)
+		}
+	}
+
+	return nil
+}
+
+// equal returns whether the patterns are defined the same way.
+func (p *Pattern) equal(other *Pattern) bool {
+	// Only the exported fields are marshalled, leaving out the compiled path.
+	this, err := json.Marshal(p)
+	if err != nil {
+		return false
+	}
+
+	that, err := json.Marshal(other)
+	if err != nil {
+		return false
+	}
+
+	return bytes.Equal(this, that)
+}
+
+// Patterns are user-defined API usages extending the built-in ones.
+type Patterns struct {
+	Version  string     `json:"version"`
+	Patterns []*Pattern `json:"patterns"`
+}
+
+// LoadPatterns loads user-defined API usages of the vendor from a YAML file and
+// validates them.
+func LoadPatterns(path string, vendor validation.Vendor) (*Patterns, error) {
+	var out Patterns
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't read the allowlist file %q", path)
+	}
+
+	if err := yaml.Unmarshal(data, &out); err != nil {
+		return nil, errors.Wrapf(err, "couldn't parse the allowlist file %q", path)
+	}
+
+	if err := out.Compile(vendor); err != nil {
+		return nil, errors.Wrapf(err, "invalid allowlist file %q", path)
+	}
+
+	return &out, nil
+}
+
+// Compile compiles the patterns for the vendor.
+func (p Patterns) Compile(vendor validation.Vendor) error {
+	names := make(map[string]struct{})
+
+	for i, pattern := range p.Patterns {
+		if _, ok := names[pattern.Name]; ok {
+			return errors.Errorf("duplicate pattern name %q", pattern.Name)
+		}
+		names[pattern.Name] = struct{}{}
+
+		if err := pattern.Validate(vendor); err != nil {
+			return errors.Wrapf(err, "invalid pattern %d", i)
+		}
+	}
+
+	return nil
+}
+
+// MatchRequest matches the request against the user-defined patterns first
+// and the built-in ones not overridden by them after, like MatchRequest does
+// with the built-in ones only. The patterns may be nil.
+func (p *Patterns) MatchRequest(vendor validation.Vendor, r *http.Request) (name string, project string, err error) {
+	if p == nil || len(p.Patterns) == 0 {
+		return MatchRequest(vendor, r)
+	}
+
+	matcher, ok := vendorMatchers[vendor]
+	if !ok {
+		return "", "", ErrNoMatch
+	}
+
+	overridden := make(map[string]bool, len(p.Patterns))
+
+	for _, pattern := range p.Patterns {
+		overridden[pattern.Name] = true
+
+		if r.Method != pattern.Method {
+			continue
+		}
+
+		if matches := pattern.pathRegexp.FindStringSubmatch(r.URL.EscapedPath()); matches != nil {
+			return pattern.Name, vendorProjects[vendor](pattern.pathRegexp, matches), nil
+		}
+	}
+
+	return matcher(r, overridden)
+}
+
+// EndpointNames returns the sorted names of the built-in and user-defined API
+// usages of the vendor, as returned by MatchRequest. The patterns may be nil.
+func (p *Patterns) EndpointNames(vendor validation.Vendor) []string {
+	names := EndpointNames(vendor)
+
+	if p != nil {
+		for _, pattern := range p.Patterns {
+			if !slices.Contains(names, pattern.Name) {
+				names = append(names, pattern.Name)
+			}
+		}
+	}
+
+	slices.Sort(names)
+
+	return names
+}
+
+// DiffPatterns returns the names of the patterns added to, removed from and
+// changed between the previous and the next patterns.
+func DiffPatterns(previous, next *Patterns) (added, removed, changed []string) {
+	if previous == nil {
+		previous = new(Patterns)
+	}
+
+	if next == nil {
+		next = new(Patterns)
+	}
+
+	previousPatterns := make(map[string]*Pattern, len(previous.Patterns))
+	for _, pattern := range previous.Patterns {
+		previousPatterns[pattern.Name] = pattern
+	}
+
+	for _, pattern := range next.Patterns {
+		previousPattern, ok := previousPatterns[pattern.Name]
+		delete(previousPatterns, pattern.Name)
+
+		switch {
+		case !ok:
+			added = append(added, pattern.Name)
+		case !pattern.equal(previousPattern):
+			changed = append(changed, pattern.Name)
+		}
+	}
+
+	for _, pattern := range previous.Patterns {
+		if _, ok := previousPatterns[pattern.Name]; ok {
+			removed = append(removed, pattern.Name)
+		}
+	}
+
+	return added, removed, changed
+}
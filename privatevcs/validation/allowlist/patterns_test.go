@@ -0,0 +1,136 @@
+package allowlist
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/spacelift-io/spcontext"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacelift-io/vcs-agent/privatevcs"
+	"github.com/spacelift-io/vcs-agent/privatevcs/validation"
+)
+
+func TestLoadPatterns(t *testing.T) {
+	t.Run("with an invalid pattern", func(t *testing.T) {
+		_, err := LoadPatterns("fixtures/invalid_patterns.yaml", validation.GitLab)
+
+		require.EqualError(t, err, "invalid allowlist file \"fixtures/invalid_patterns.yaml\": invalid pattern 0: could not compile pattern \"Invalid\": invalid path matcher: error parsing regexp: missing closing ]: `[`")
+	})
+
+	t.Run("with a duplicate pattern", func(t *testing.T) {
+		patterns := &Patterns{Patterns: []*Pattern{
+			{Name: "Duplicate", Method: http.MethodGet, Path: ".*", NoProject: true},
+			{Name: "Duplicate", Method: http.MethodPost, Path: ".*", NoProject: true},
+		}}
+
+		require.EqualError(t, patterns.Compile(validation.GitLab), `duplicate pattern name "Duplicate"`)
+	})
+
+	t.Run("without a method", func(t *testing.T) {
+		patterns := &Patterns{Patterns: []*Pattern{{Name: "Any method", Path: ".*"}}}
+
+		require.EqualError(t, patterns.Compile(validation.GitLab), `invalid pattern 0: method of pattern "Any method" is required`)
+	})
+
+	t.Run("without the project groups", func(t *testing.T) {
+		patterns := &Patterns{Patterns: []*Pattern{{Name: "Get Repository", Method: http.MethodGet, Path: "^/rest/api/1.0/projects/(?P<projectKey>[^/]+)/repos/[^/]+$"}}}
+
+		require.EqualError(t, patterns.Compile(validation.BitbucketDatacenter), `invalid pattern 0: path of pattern "Get Repository" doesn't capture the "repositorySlug" group of the project, set no_project if it isn't scoped to one`)
+	})
+
+	t.Run("without a project", func(t *testing.T) {
+		patterns := &Patterns{Patterns: []*Pattern{{Name: "Get Version", Method: http.MethodGet, Path: "^/api/v4/version$", NoProject: true}}}
+
+		require.NoError(t, patterns.Compile(validation.GitLab))
+	})
+
+	t.Run("with an unsupported vendor", func(t *testing.T) {
+		patterns := &Patterns{Patterns: []*Pattern{{Name: "Get Version", Method: http.MethodGet, Path: "^/api/v4/version$", NoProject: true}}}
+
+		require.EqualError(t, patterns.Compile("gitea"), `invalid pattern 0: unsupported vendor "gitea"`)
+	})
+
+	t.Run("with valid patterns", func(t *testing.T) {
+		patterns, err := LoadPatterns("fixtures/patterns.yaml", validation.GitLab)
+
+		require.NoError(t, err)
+		require.Len(t, patterns.Patterns, 3)
+		require.True(t, patterns.Patterns[2].NoProject)
+	})
+}
+
+func TestListWithPatterns(t *testing.T) {
+	patterns, err := LoadPatterns("fixtures/patterns.yaml", validation.GitLab)
+	require.NoError(t, err)
+
+	// The project override is narrower than the built-in pattern.
+	patterns.Patterns[1].Path = "^/api/v4/projects/(?P<project>infra%2F[^/]+)$"
+	require.NoError(t, patterns.Compile(validation.GitLab))
+
+	list, err := NewWithPatterns("^infra/", patterns)
+	require.NoError(t, err)
+
+	validate := func(method, path string) error {
+		req, err := http.NewRequest(method, "https://gitlab.example.com"+path, nil)
+		require.NoError(t, err)
+
+		_, err = list.Validate(spcontext.New(log.NewNopLogger()), validation.GitLab, req)
+		return err
+	}
+
+	code := func(err error) privatevcs.ErrorCode {
+		var validationErr *validation.Error
+		require.ErrorAs(t, err, &validationErr)
+		return validationErr.Code
+	}
+
+	t.Run("with an added pattern", func(t *testing.T) {
+		require.NoError(t, validate(http.MethodGet, "/api/v4/projects/infra%2Fnetwork/languages"))
+		require.Equal(t, privatevcs.ErrorCode_ERROR_CODE_PROJECT_NOT_ALLOWED, code(validate(http.MethodGet, "/api/v4/projects/apps%2Fweb/languages")))
+	})
+
+	t.Run("with an overridden pattern", func(t *testing.T) {
+		require.NoError(t, validate(http.MethodGet, "/api/v4/projects/infra%2Fnetwork"))
+		require.Equal(t, privatevcs.ErrorCode_ERROR_CODE_NO_ALLOWLIST_MATCH, code(validate(http.MethodGet, "/api/v4/projects/apps%2Fweb")), "the built-in pattern shouldn't be used")
+	})
+
+	t.Run("with a built-in pattern", func(t *testing.T) {
+		require.NoError(t, validate(http.MethodGet, "/api/v4/projects/infra%2Fnetwork/repository/branches"))
+	})
+}
+
+func TestPatternsEndpointNames(t *testing.T) {
+	patterns, err := LoadPatterns("fixtures/patterns.yaml", validation.GitLab)
+	require.NoError(t, err)
+
+	names := patterns.EndpointNames(validation.GitLab)
+
+	require.Contains(t, names, "Get Repository Languages")
+	require.Contains(t, names, "Get Version")
+	require.Len(t, names, len(EndpointNames(validation.GitLab))+2, "overridden built-in patterns should only be listed once")
+	require.IsNonDecreasing(t, names)
+
+	require.Equal(t, EndpointNames(validation.GitLab), (*Patterns)(nil).EndpointNames(validation.GitLab))
+}
+
+func TestDiffPatterns(t *testing.T) {
+	previous := &Patterns{Patterns: []*Pattern{
+		{Name: "Kept", Method: http.MethodGet, Path: ".*"},
+		{Name: "Changed", Method: http.MethodGet, Path: ".*"},
+		{Name: "Removed", Method: http.MethodGet, Path: ".*"},
+	}}
+
+	next := &Patterns{Patterns: []*Pattern{
+		{Name: "Added", Method: http.MethodGet, Path: ".*"},
+		{Name: "Changed", Method: http.MethodPost, Path: ".*"},
+		{Name: "Kept", Method: http.MethodGet, Path: ".*"},
+	}}
+
+	added, removed, changed := DiffPatterns(previous, next)
+
+	require.Equal(t, []string{"Added"}, added)
+	require.Equal(t, []string{"Removed"}, removed)
+	require.Equal(t, []string{"Changed"}, changed)
+}
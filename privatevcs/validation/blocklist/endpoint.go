@@ -0,0 +1,94 @@
+package blocklist
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+
+	"github.com/pkg/errors"
+
+	"github.com/spacelift-io/vcs-agent/privatevcs/validation"
+	"github.com/spacelift-io/vcs-agent/privatevcs/validation/allowlist"
+)
+
+// ProjectMatcher matches the project targeted by the request, as extracted
+// using the vendor patterns of the allowlist, including the user-defined ones.
+type ProjectMatcher struct {
+	// The regular expression the project has to match.
+	Include string `json:"include"`
+
+	// The regular expression the project must not match.
+	Exclude string `json:"exclude"`
+
+	includeRegexp, excludeRegexp *regexp.Regexp
+}
+
+func (m *ProjectMatcher) compile() error {
+	if m.Include == "" && m.Exclude == "" {
+		return errors.New("include or exclude is required")
+	}
+
+	var err error
+	if m.Include != "" {
+		if m.includeRegexp, err = regexp.Compile(m.Include); err != nil {
+			return errors.Wrap(err, "invalid include")
+		}
+	}
+
+	if m.Exclude != "" {
+		if m.excludeRegexp, err = regexp.Compile(m.Exclude); err != nil {
+			return errors.Wrap(err, "invalid exclude")
+		}
+	}
+
+	return nil
+}
+
+// matches returns true if the project is included and not excluded. Requests
+// not scoped to a project never match.
+func (m *ProjectMatcher) matches(project string) bool {
+	if project == "" {
+		return false
+	}
+
+	if m.includeRegexp != nil && !m.includeRegexp.MatchString(project) {
+		return false
+	}
+
+	return m.excludeRegexp == nil || !m.excludeRegexp.MatchString(project)
+}
+
+// endpointMatch is the vendor endpoint matched by a request, resolved on first
+// use and shared by all rules of the list.
+type endpointMatch struct {
+	vendor   validation.Vendor
+	patterns *allowlist.Patterns
+	req      *http.Request
+
+	resolved bool
+	ok       bool
+	name     string
+	project  string
+}
+
+// get returns the name of the endpoint and the unescaped project, if the
+// request matches a known endpoint of the vendor.
+func (m *endpointMatch) get() (name, project string, ok bool) {
+	if m == nil {
+		return "", "", false
+	}
+
+	if !m.resolved {
+		m.resolved = true
+
+		name, project, err := m.patterns.MatchRequest(m.vendor, m.req)
+		if err == nil {
+			if unescaped, err := url.PathUnescape(project); err == nil {
+				project = unescaped
+			}
+			m.ok, m.name, m.project = true, name, project
+		}
+	}
+
+	return m.name, m.project, m.ok
+}
@@ -8,18 +8,31 @@ import (
 	"github.com/spacelift-io/spcontext"
 	"gopkg.in/yaml.v3"
 
+	"github.com/spacelift-io/vcs-agent/privatevcs"
 	"github.com/spacelift-io/vcs-agent/privatevcs/validation"
+	"github.com/spacelift-io/vcs-agent/privatevcs/validation/allowlist"
 )
 
 // List is an explicit list of blocked VCS request patterns.
 type List struct {
 	Version string  `json:"version"`
 	Rules   []*Rule `json:"rules"`
+
+	// patterns are the user-defined allowlist patterns the endpoint and the
+	// project of requests are matched with, besides the built-in ones.
+	patterns *allowlist.Patterns
 }
 
 // Load loads a blocklist from a YAML file and validates it.
 func Load(path string) (*List, error) {
-	var out List
+	return LoadWithPatterns(path, nil)
+}
+
+// LoadWithPatterns loads a blocklist from a YAML file and validates it,
+// matching the endpoint and the project of requests with the user-defined
+// allowlist patterns besides the built-in ones.
+func LoadWithPatterns(path string, patterns *allowlist.Patterns) (*List, error) {
+	out := List{patterns: patterns}
 
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -57,12 +70,61 @@ func (l List) Compile() error {
 
 // Validate validates the request against the blocklist. If the request matches
 // any rule, it returns an error.
-func (l List) Validate(ctx *spcontext.Context, _ validation.Vendor, r *http.Request) (*spcontext.Context, error) {
+func (l List) Validate(ctx *spcontext.Context, vendor validation.Vendor, r *http.Request) (*spcontext.Context, error) {
+	ctx, _, err := l.Decide(ctx, vendor, r)
+	return ctx, err
+}
+
+// Decide validates the request against the blocklist. It only decides on the
+// requests it blocks.
+func (l List) Decide(ctx *spcontext.Context, vendor validation.Vendor, r *http.Request) (*spcontext.Context, bool, error) {
+	endpoint := &endpointMatch{vendor: vendor, patterns: l.patterns, req: r}
+
 	for _, rule := range l.Rules {
-		if rule.Matches(r) {
-			return ctx.With("blocked_by", rule.Name), errors.Errorf("request blocked by rule %q", rule.Name)
+		if rule.matches(r, endpoint) {
+			name, project, _ := endpoint.get()
+			if name != "" {
+				ctx = ctx.With("name", name, "project", project)
+			}
+
+			return ctx.With("blocked_by", rule.Name), true, &validation.Error{
+				Code:     privatevcs.ErrorCode_ERROR_CODE_BLOCKED_BY_RULE,
+				Rule:     rule.Name,
+				Endpoint: name,
+				Project:  project,
+				Err:      errors.Errorf("request blocked by rule %q", rule.Name),
+			}
+		}
+	}
+
+	return ctx, false, nil
+}
+
+// Diff returns the names of the rules added to, removed from and changed
+// between the previous and the next list.
+func Diff(previous, next *List) (added, removed, changed []string) {
+	previousRules := make(map[string]*Rule, len(previous.Rules))
+	for _, rule := range previous.Rules {
+		previousRules[rule.Name] = rule
+	}
+
+	for _, rule := range next.Rules {
+		previousRule, ok := previousRules[rule.Name]
+		delete(previousRules, rule.Name)
+
+		switch {
+		case !ok:
+			added = append(added, rule.Name)
+		case !rule.equal(previousRule):
+			changed = append(changed, rule.Name)
+		}
+	}
+
+	for _, rule := range previous.Rules {
+		if _, ok := previousRules[rule.Name]; ok {
+			removed = append(removed, rule.Name)
 		}
 	}
 
-	return ctx, nil
+	return added, removed, changed
 }
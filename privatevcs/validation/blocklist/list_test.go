@@ -2,6 +2,9 @@ package blocklist_test
 
 import (
 	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/go-kit/log"
@@ -9,6 +12,8 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/spacelift-io/vcs-agent/privatevcs/validation"
+	"github.com/spacelift-io/vcs-agent/privatevcs/validation/allowlist"
 	"github.com/spacelift-io/vcs-agent/privatevcs/validation/blocklist"
 )
 
@@ -48,6 +53,20 @@ func TestListLoad(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Len(t, sut.Rules, 2)
 	})
+
+	t.Run("with request conditions", func(t *testing.T) {
+		path := "fixtures/conditions.yaml"
+
+		sut, err := blocklist.Load(path)
+		require.NoError(t, err)
+
+		req, err := http.NewRequest("POST", "https://example.com/repos/a/b/statuses/abc", strings.NewReader(`{"state": "success", "context": "ci"}`))
+		require.NoError(t, err, "failed to create request")
+
+		_, err = sut.Validate(spcontext.New(log.NewNopLogger()), "", req)
+
+		assert.EqualError(t, err, `request blocked by rule "Successful statuses outside Spacelift"`)
+	})
 }
 
 func TestListValidate(t *testing.T) {
@@ -102,4 +121,98 @@ func TestListValidate(t *testing.T) {
 
 		assert.EqualError(t, err, `request blocked by rule "Matching"`)
 	})
-}
\ No newline at end of file
+
+	t.Run("with project-aware rules", func(t *testing.T) {
+		ctx := spcontext.New(log.NewNopLogger())
+
+		sut := new(blocklist.List)
+		sut.Rules = []*blocklist.Rule{{
+			Name:     "SecOps commit statuses",
+			Method:   ".*",
+			Path:     ".*",
+			Endpoint: "^Set Commit Status$",
+			Project:  &blocklist.ProjectMatcher{Include: "^secops/", Exclude: "^secops/public-"},
+		}}
+
+		err := sut.Compile()
+		require.NoError(t, err, "failed to compile rules")
+
+		validate := func(vendor validation.Vendor, path string) error {
+			req, err := http.NewRequest("POST", "https://example.com"+path, nil)
+			require.NoError(t, err, "failed to create request")
+
+			_, err = sut.Validate(ctx, vendor, req)
+			return err
+		}
+
+		err = validate(validation.GitLab, "/api/v4/projects/secops%2Finfra/statuses/abc")
+
+		var validationErr *validation.Error
+		require.ErrorAs(t, err, &validationErr)
+		assert.Equal(t, "SecOps commit statuses", validationErr.Rule)
+		assert.Equal(t, "Set Commit Status", validationErr.Endpoint)
+		assert.Equal(t, "secops/infra", validationErr.Project)
+
+		assert.NoError(t, validate(validation.GitLab, "/api/v4/projects/secops%2Fpublic-docs/statuses/abc"), "excluded project")
+		assert.NoError(t, validate(validation.GitLab, "/api/v4/projects/platform%2Finfra/statuses/abc"), "other project")
+		assert.NoError(t, validate(validation.GitLab, "/api/v4/projects/secops%2Finfra/environments"), "other endpoint")
+		assert.NoError(t, validate(validation.BitbucketDatacenter, "/api/v4/projects/secops%2Finfra/statuses/abc"), "other vendor")
+	})
+
+	t.Run("with user-defined allowlist patterns", func(t *testing.T) {
+		ctx := spcontext.New(log.NewNopLogger())
+
+		patterns := &allowlist.Patterns{Patterns: []*allowlist.Pattern{{
+			Name:   "Get Repository Languages",
+			Method: http.MethodGet,
+			Path:   "^/api/v4/projects/(?P<project>[^/]+)/languages$",
+		}}}
+		require.NoError(t, patterns.Compile(validation.GitLab), "failed to compile patterns")
+
+		path := filepath.Join(t.TempDir(), "blocklist.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(`
+rules:
+  - name: SecOps languages
+    method: .*
+    path: .*
+    endpoint: ^Get Repository Languages$
+    project:
+      include: ^secops/
+`), 0o600))
+
+		sut, err := blocklist.LoadWithPatterns(path, patterns)
+		require.NoError(t, err, "failed to load blocklist")
+
+		req, err := http.NewRequest(http.MethodGet, "https://example.com/api/v4/projects/secops%2Finfra/languages", nil)
+		require.NoError(t, err, "failed to create request")
+
+		_, err = sut.Validate(ctx, validation.GitLab, req)
+
+		var validationErr *validation.Error
+		require.ErrorAs(t, err, &validationErr)
+		assert.Equal(t, "Get Repository Languages", validationErr.Endpoint)
+		assert.Equal(t, "secops/infra", validationErr.Project)
+	})
+}
+
+func TestDiff(t *testing.T) {
+	previous := &blocklist.List{Rules: []*blocklist.Rule{
+		{Name: "Kept", Method: "GET", Path: ".*"},
+		{Name: "Changed", Method: "GET", Path: ".*"},
+		{Name: "Removed", Method: "GET", Path: ".*"},
+	}}
+	require.NoError(t, previous.Compile())
+
+	next := &blocklist.List{Rules: []*blocklist.Rule{
+		{Name: "Added", Method: "GET", Path: ".*"},
+		{Name: "Changed", Method: "GET", Path: ".*", Query: []*blocklist.Matcher{{Name: "recursive", Value: "1"}}},
+		{Name: "Kept", Method: "GET", Path: ".*"},
+	}}
+	require.NoError(t, next.Compile())
+
+	added, removed, changed := blocklist.Diff(previous, next)
+
+	assert.Equal(t, []string{"Added"}, added)
+	assert.Equal(t, []string{"Removed"}, removed)
+	assert.Equal(t, []string{"Changed"}, changed)
+}
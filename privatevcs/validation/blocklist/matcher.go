@@ -0,0 +1,136 @@
+package blocklist
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Matcher matches a named part of the request against a regular expression.
+type Matcher struct {
+	// The name of the query parameter or header, or the dot separated path of
+	// the JSON body field, e.g. "commit.author.0.name".
+	Name string `json:"name"`
+
+	// The regular expression to match the values against.
+	Value string `json:"value"`
+
+	// Whether the matcher should match when none of the values match,
+	// including when there are no values at all.
+	Negate bool `json:"negate"`
+
+	valueRegexp *regexp.Regexp
+}
+
+func (m *Matcher) compile() error {
+	if m.Name == "" {
+		return errors.New("name is required")
+	}
+
+	var err error
+	m.valueRegexp, err = regexp.Compile(m.Value)
+	return errors.Wrap(err, "invalid value")
+}
+
+// matches returns true if any of the values matches, or if none does for a
+// negated matcher.
+func (m *Matcher) matches(values []string) bool {
+	for _, value := range values {
+		if m.valueRegexp.MatchString(value) {
+			return !m.Negate
+		}
+	}
+
+	return m.Negate
+}
+
+func compileMatchers(kind string, matchers []*Matcher) error {
+	for i, matcher := range matchers {
+		if err := matcher.compile(); err != nil {
+			return errors.Wrapf(err, "invalid %s matcher %d", kind, i)
+		}
+	}
+
+	return nil
+}
+
+// requestBody returns the body of the request without consuming it.
+func requestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close() //nolint:errcheck // the body is in memory
+
+		return io.ReadAll(body)
+	}
+
+	data, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(data))
+
+	return data, nil
+}
+
+// jsonBody is a request body decoded on first use.
+type jsonBody struct {
+	req     *http.Request
+	decoded bool
+	value   any
+}
+
+// values returns the scalar value at the dot separated path of the body, or
+// nothing if the body isn't JSON or the field is missing or not a scalar.
+func (b *jsonBody) values(path string) []string {
+	if !b.decoded {
+		b.decoded = true
+
+		if data, err := requestBody(b.req); err == nil && len(data) > 0 {
+			decoder := json.NewDecoder(bytes.NewReader(data))
+			decoder.UseNumber()
+			if err := decoder.Decode(&b.value); err != nil {
+				b.value = nil
+			}
+		}
+	}
+
+	value := b.value
+	for _, key := range strings.Split(path, ".") {
+		switch current := value.(type) {
+		case map[string]any:
+			value = current[key]
+		case []any:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(current) {
+				return nil
+			}
+			value = current[index]
+		default:
+			return nil
+		}
+	}
+
+	switch value := value.(type) {
+	case string:
+		return []string{value}
+	case json.Number:
+		return []string{value.String()}
+	case bool:
+		return []string{strconv.FormatBool(value)}
+	default:
+		return nil
+	}
+}
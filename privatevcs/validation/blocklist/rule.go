@@ -1,13 +1,16 @@
 package blocklist
 
 import (
+	"bytes"
+	"encoding/json"
 	"net/http"
 	"regexp"
 
 	"github.com/pkg/errors"
 )
 
-// Rule is a single rule in a blocklist.
+// Rule is a single rule in a blocklist. The rule matches a request only if all
+// of its conditions do.
 type Rule struct {
 	// The name of the rule.
 	Name string `json:"name"`
@@ -18,12 +21,77 @@ type Rule struct {
 	// The regular expression to match the path against.
 	Path string `json:"path"`
 
-	methodRegexp, pathRegexp *regexp.Regexp
+	// Matchers for the query parameters of the request.
+	Query []*Matcher `json:"query"`
+
+	// Matchers for the headers of the request.
+	Headers []*Matcher `json:"headers"`
+
+	// Matchers for the fields of the JSON request body.
+	Body []*Matcher `json:"body"`
+
+	// The regular expression to match the name of the vendor endpoint
+	// against, as known to the allowlist, e.g. "Create commit status".
+	Endpoint string `json:"endpoint"`
+
+	// Matcher for the project targeted by the request.
+	Project *ProjectMatcher `json:"project"`
+
+	methodRegexp, pathRegexp, endpointRegexp *regexp.Regexp
 }
 
-// Matches returns true if the rule matches the given request.
+// Matches returns true if the rule matches the given request. Rules matching
+// the endpoint or the project need the vendor of the request, so they only
+// match requests validated by a List.
 func (r *Rule) Matches(req *http.Request) bool {
-	return r.methodRegexp.MatchString(req.Method) && r.pathRegexp.MatchString(req.URL.Path)
+	return r.matches(req, nil)
+}
+
+func (r *Rule) matches(req *http.Request, endpoint *endpointMatch) bool {
+	if !r.methodRegexp.MatchString(req.Method) || !r.pathRegexp.MatchString(req.URL.Path) {
+		return false
+	}
+
+	if r.endpointRegexp != nil || r.Project != nil {
+		name, project, ok := endpoint.get()
+		if !ok {
+			return false
+		}
+
+		if r.endpointRegexp != nil && !r.endpointRegexp.MatchString(name) {
+			return false
+		}
+
+		if r.Project != nil && !r.Project.matches(project) {
+			return false
+		}
+	}
+
+	if len(r.Query) > 0 {
+		query := req.URL.Query()
+
+		for _, matcher := range r.Query {
+			if !matcher.matches(query[matcher.Name]) {
+				return false
+			}
+		}
+	}
+
+	for _, matcher := range r.Headers {
+		if !matcher.matches(req.Header.Values(matcher.Name)) {
+			return false
+		}
+	}
+
+	body := &jsonBody{req: req}
+
+	for _, matcher := range r.Body {
+		if !matcher.matches(body.values(matcher.Name)) {
+			return false
+		}
+	}
+
+	return true
 }
 
 // Validate compiles and validates the rule.
@@ -43,5 +111,46 @@ func (r *Rule) compile() error {
 	}
 
 	r.pathRegexp, err = regexp.Compile(r.Path)
-	return errors.Wrapf(err, "invalid path matcher")
+	if err != nil {
+		return errors.Wrapf(err, "invalid path matcher")
+	}
+
+	if r.Endpoint != "" {
+		if r.endpointRegexp, err = regexp.Compile(r.Endpoint); err != nil {
+			return errors.Wrapf(err, "invalid endpoint matcher")
+		}
+	}
+
+	if r.Project != nil {
+		if err := r.Project.compile(); err != nil {
+			return errors.Wrap(err, "invalid project matcher")
+		}
+	}
+
+	if err := compileMatchers("query", r.Query); err != nil {
+		return err
+	}
+
+	if err := compileMatchers("header", r.Headers); err != nil {
+		return err
+	}
+
+	return compileMatchers("body", r.Body)
+}
+
+// equal returns whether the rules are defined the same way.
+func (r *Rule) equal(other *Rule) bool {
+	// The configuration only consists of exported fields, which are the ones
+	// marshalled, while the compiled matchers are left out.
+	this, err := json.Marshal(r)
+	if err != nil {
+		return false
+	}
+
+	that, err := json.Marshal(other)
+	if err != nil {
+		return false
+	}
+
+	return bytes.Equal(this, that)
 }
@@ -1,7 +1,9 @@
 package blocklist_test
 
 import (
+	"io"
 	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -42,6 +44,36 @@ func TestRuleValidate(t *testing.T) {
 				assert.EqualError(t, err, "could not compile rule \"foo\": invalid path matcher: error parsing regexp: missing closing ]: `[`")
 			})
 
+			t.Run("with an invalid query matcher", func(t *testing.T) {
+				sut := new(blocklist.Rule)
+				sut.Name = "foo"
+				sut.Query = []*blocklist.Matcher{{Name: "recursive", Value: "["}}
+
+				err := sut.Validate()
+
+				assert.EqualError(t, err, "could not compile rule \"foo\": invalid query matcher 0: invalid value: error parsing regexp: missing closing ]: `[`")
+			})
+
+			t.Run("with an empty project matcher", func(t *testing.T) {
+				sut := new(blocklist.Rule)
+				sut.Name = "foo"
+				sut.Project = new(blocklist.ProjectMatcher)
+
+				err := sut.Validate()
+
+				assert.EqualError(t, err, `could not compile rule "foo": invalid project matcher: include or exclude is required`)
+			})
+
+			t.Run("with an unnamed body matcher", func(t *testing.T) {
+				sut := new(blocklist.Rule)
+				sut.Name = "foo"
+				sut.Body = []*blocklist.Matcher{{Value: ".*"}}
+
+				err := sut.Validate()
+
+				assert.EqualError(t, err, `could not compile rule "foo": invalid body matcher 0: name is required`)
+			})
+
 			t.Run("with valid path regexp", func(t *testing.T) {
 				sut := new(blocklist.Rule)
 				sut.Name = "foo"
@@ -120,4 +152,80 @@ func TestRuleMatches(t *testing.T) {
 			assert.False(t, match)
 		})
 	})
-}
\ No newline at end of file
+}
+
+func TestRuleMatchesConditions(t *testing.T) {
+	newRequest := func(t *testing.T, target, body string) *http.Request {
+		req, err := http.NewRequest("POST", target, strings.NewReader(body))
+		require.NoError(t, err, "failed to create request")
+		req.Header.Set("Content-Type", "application/json")
+		return req
+	}
+
+	newRule := func(t *testing.T, rule *blocklist.Rule) *blocklist.Rule {
+		rule.Name, rule.Method, rule.Path = "foo", ".*", ".*"
+		require.NoError(t, rule.Validate(), "failed to validate rule")
+		return rule
+	}
+
+	t.Run("with a query matcher", func(t *testing.T) {
+		sut := newRule(t, &blocklist.Rule{Query: []*blocklist.Matcher{{Name: "recursive", Value: "^(1|true)$"}}})
+
+		assert.True(t, sut.Matches(newRequest(t, "https://example.com/trees/main?recursive=1", "")))
+		assert.True(t, sut.Matches(newRequest(t, "https://example.com/trees/main?recursive=0&recursive=true", "")))
+		assert.False(t, sut.Matches(newRequest(t, "https://example.com/trees/main?recursive=0", "")))
+		assert.False(t, sut.Matches(newRequest(t, "https://example.com/trees/main", "")))
+	})
+
+	t.Run("with a header matcher", func(t *testing.T) {
+		sut := newRule(t, &blocklist.Rule{Headers: []*blocklist.Matcher{{Name: "content-type", Value: "json"}}})
+
+		assert.True(t, sut.Matches(newRequest(t, "https://example.com/", "")))
+
+		req := newRequest(t, "https://example.com/", "")
+		req.Header.Del("Content-Type")
+		assert.False(t, sut.Matches(req))
+	})
+
+	t.Run("with body matchers", func(t *testing.T) {
+		// Blocks successful commit statuses unless reported for Spacelift.
+		sut := newRule(t, &blocklist.Rule{Body: []*blocklist.Matcher{
+			{Name: "state", Value: "^success$"},
+			{Name: "context", Value: "^spacelift/", Negate: true},
+		}})
+
+		assert.True(t, sut.Matches(newRequest(t, "https://example.com/", `{"state": "success", "context": "ci/build"}`)))
+		assert.True(t, sut.Matches(newRequest(t, "https://example.com/", `{"state": "success"}`)))
+		assert.False(t, sut.Matches(newRequest(t, "https://example.com/", `{"state": "success", "context": "spacelift/stack"}`)))
+		assert.False(t, sut.Matches(newRequest(t, "https://example.com/", `{"state": "failure", "context": "ci/build"}`)))
+		assert.False(t, sut.Matches(newRequest(t, "https://example.com/", `not json`)))
+	})
+
+	t.Run("with a nested body matcher", func(t *testing.T) {
+		sut := newRule(t, &blocklist.Rule{Body: []*blocklist.Matcher{{Name: "commits.1.count", Value: "^42$"}}})
+
+		assert.True(t, sut.Matches(newRequest(t, "https://example.com/", `{"commits": [{}, {"count": 42}]}`)))
+		assert.False(t, sut.Matches(newRequest(t, "https://example.com/", `{"commits": [{"count": 42}]}`)))
+	})
+
+	t.Run("leaves the body readable", func(t *testing.T) {
+		sut := newRule(t, &blocklist.Rule{Body: []*blocklist.Matcher{{Name: "state", Value: ".*"}}})
+
+		for _, req := range []*http.Request{
+			newRequest(t, "https://example.com/", `{"state": "success"}`),
+			{Method: "POST", URL: newRequest(t, "https://example.com/", "").URL, Body: io.NopCloser(strings.NewReader(`{"state": "success"}`))},
+		} {
+			assert.True(t, sut.Matches(req))
+
+			body, err := io.ReadAll(req.Body)
+			require.NoError(t, err)
+			assert.Equal(t, `{"state": "success"}`, string(body))
+		}
+	})
+
+	t.Run("with a project matcher outside of a list", func(t *testing.T) {
+		sut := newRule(t, &blocklist.Rule{Project: &blocklist.ProjectMatcher{Include: ".*"}})
+
+		assert.False(t, sut.Matches(newRequest(t, "https://example.com/api/v4/projects/a%2Fb", "")), "the vendor is unknown")
+	})
+}
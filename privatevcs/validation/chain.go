@@ -0,0 +1,76 @@
+package validation
+
+import (
+	"net/http"
+
+	"github.com/pkg/errors"
+	"github.com/spacelift-io/spcontext"
+)
+
+// ChainMode decides how the strategies of a chain are combined.
+type ChainMode string
+
+const (
+	// ChainModeAll requires the request to pass all strategies.
+	ChainModeAll ChainMode = "all"
+
+	// ChainModeFirst lets the first strategy deciding on the request allow or
+	// block it. Requests no strategy decides on are allowed.
+	ChainModeFirst ChainMode = "first"
+)
+
+// Decider is implemented by strategies which may have no opinion on some
+// requests. Strategies which aren't deciders decide on all requests.
+type Decider interface {
+	Strategy
+
+	// Decide validates the request like Validate, but returns decided false
+	// if the strategy has no opinion on the request, leaving the decision to
+	// the next strategy in a ChainModeFirst chain.
+	Decide(*spcontext.Context, Vendor, *http.Request) (ctx *spcontext.Context, decided bool, err error)
+}
+
+// Chain is a strategy evaluating an ordered chain of strategies. The context
+// is passed from one strategy to the next, so that the fields added by each
+// of them are kept.
+type Chain struct {
+	Mode       ChainMode
+	Strategies []Strategy
+}
+
+// NewChain creates a chain of the strategies.
+func NewChain(mode ChainMode, strategies ...Strategy) (*Chain, error) {
+	switch mode {
+	case ChainModeAll, ChainModeFirst:
+	default:
+		return nil, errors.Errorf("invalid chain mode %q, use %q or %q", mode, ChainModeAll, ChainModeFirst)
+	}
+
+	return &Chain{Mode: mode, Strategies: strategies}, nil
+}
+
+// Validate validates the request against the strategies of the chain.
+func (c *Chain) Validate(ctx *spcontext.Context, vendor Vendor, req *http.Request) (*spcontext.Context, error) {
+	for _, strategy := range c.Strategies {
+		var (
+			decided = true
+			err     error
+		)
+
+		if decider, ok := strategy.(Decider); ok && c.Mode == ChainModeFirst {
+			ctx, decided, err = decider.Decide(ctx, vendor, req)
+		} else {
+			ctx, err = strategy.Validate(ctx, vendor, req)
+		}
+
+		if err != nil {
+			return ctx, err
+		}
+
+		if decided && c.Mode == ChainModeFirst {
+			return ctx, nil
+		}
+	}
+
+	return ctx, nil
+}
@@ -0,0 +1,66 @@
+package validation_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/spacelift-io/spcontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/spacelift-io/vcs-agent/privatevcs"
+	"github.com/spacelift-io/vcs-agent/privatevcs/validation"
+	"github.com/spacelift-io/vcs-agent/privatevcs/validation/allowlist"
+	"github.com/spacelift-io/vcs-agent/privatevcs/validation/blocklist"
+)
+
+func TestChain(t *testing.T) {
+	allowed, err := allowlist.New("^infra/")
+	require.NoError(t, err, "failed to create allowlist")
+
+	blocked := &blocklist.List{Rules: []*blocklist.Rule{
+		{Name: "No statuses for secrets", Method: "POST", Path: "secrets/statuses/"},
+		{Name: "No unknown deletes", Method: "DELETE", Path: "^/api/v4/unknown/"},
+	}}
+	require.NoError(t, blocked.Compile(), "failed to compile blocklist")
+
+	validate := func(t *testing.T, mode validation.ChainMode, method, path string) error {
+		chain, err := validation.NewChain(mode, allowed, blocked)
+		require.NoError(t, err)
+
+		req, err := http.NewRequest(method, "https://gitlab.example.com"+path, nil)
+		require.NoError(t, err, "failed to create request")
+
+		_, err = chain.Validate(spcontext.New(log.NewNopLogger()), validation.GitLab, req)
+		return err
+	}
+
+	code := func(err error) privatevcs.ErrorCode {
+		var validationErr *validation.Error
+		if !assert.ErrorAs(t, err, &validationErr) {
+			return privatevcs.ErrorCode_ERROR_CODE_UNKNOWN
+		}
+		return validationErr.Code
+	}
+
+	t.Run("with all strategies required", func(t *testing.T) {
+		assert.NoError(t, validate(t, validation.ChainModeAll, "POST", "/api/v4/projects/infra%2Fnetwork/statuses/abc"))
+		assert.Equal(t, privatevcs.ErrorCode_ERROR_CODE_BLOCKED_BY_RULE, code(validate(t, validation.ChainModeAll, "POST", "/api/v4/projects/infra%2Fsecrets/statuses/abc")))
+		assert.Equal(t, privatevcs.ErrorCode_ERROR_CODE_PROJECT_NOT_ALLOWED, code(validate(t, validation.ChainModeAll, "POST", "/api/v4/projects/apps%2Fweb/statuses/abc")))
+		assert.Equal(t, privatevcs.ErrorCode_ERROR_CODE_NO_ALLOWLIST_MATCH, code(validate(t, validation.ChainModeAll, "GET", "/api/v4/unknown/")))
+	})
+
+	t.Run("with the first decision winning", func(t *testing.T) {
+		assert.NoError(t, validate(t, validation.ChainModeFirst, "POST", "/api/v4/projects/infra%2Fsecrets/statuses/abc"), "allowed by the allowlist")
+		assert.Equal(t, privatevcs.ErrorCode_ERROR_CODE_PROJECT_NOT_ALLOWED, code(validate(t, validation.ChainModeFirst, "POST", "/api/v4/projects/apps%2Fweb/statuses/abc")))
+		assert.Equal(t, privatevcs.ErrorCode_ERROR_CODE_BLOCKED_BY_RULE, code(validate(t, validation.ChainModeFirst, "DELETE", "/api/v4/unknown/")), "left to the blocklist")
+		assert.NoError(t, validate(t, validation.ChainModeFirst, "GET", "/api/v4/unknown/"), "no strategy decided")
+	})
+
+	t.Run("with an invalid mode", func(t *testing.T) {
+		_, err := validation.NewChain("any")
+
+		assert.EqualError(t, err, `invalid chain mode "any", use "all" or "first"`)
+	})
+}
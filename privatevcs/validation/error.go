@@ -0,0 +1,29 @@
+package validation
+
+import "github.com/spacelift-io/vcs-agent/privatevcs"
+
+// Error is returned by strategies to explain why a request has been blocked.
+type Error struct {
+	// Code classifies the reason why the request has been blocked.
+	Code privatevcs.ErrorCode
+
+	// Rule is the name of the rule which blocked the request, if any.
+	Rule string
+
+	// Endpoint is the name of the endpoint the request matched, if any.
+	Endpoint string
+
+	// Project is the project targeted by the request, if known.
+	Project string
+
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,38 @@
+package validation
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"github.com/spacelift-io/spcontext"
+)
+
+// Reloadable is a strategy delegating to another one, which can be replaced
+// while requests are being validated. Each request is validated entirely by
+// either the previous or the new strategy.
+type Reloadable struct {
+	current atomic.Pointer[Strategy]
+}
+
+// NewReloadable creates a reloadable strategy delegating to strategy.
+func NewReloadable(strategy Strategy) *Reloadable {
+	out := new(Reloadable)
+	out.current.Store(&strategy)
+
+	return out
+}
+
+// Current returns the strategy requests are currently validated with.
+func (r *Reloadable) Current() Strategy {
+	return *r.current.Load()
+}
+
+// Swap replaces the strategy and returns the previous one.
+func (r *Reloadable) Swap(strategy Strategy) Strategy {
+	return *r.current.Swap(&strategy)
+}
+
+// Validate validates the request with the current strategy.
+func (r *Reloadable) Validate(ctx *spcontext.Context, vendor Vendor, req *http.Request) (*spcontext.Context, error) {
+	return r.Current().Validate(ctx, vendor, req)
+}
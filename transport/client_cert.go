@@ -0,0 +1,196 @@
+package transport
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/youmark/pkcs8"
+)
+
+// ClientCertificate is a client certificate for mutual TLS, supplied either as
+// base64 or as files. The zero value presents no certificate.
+type ClientCertificate struct {
+	// CertBase64 and KeyBase64 are a base64 encoded PEM certificate chain and
+	// private key.
+	CertBase64 string
+	KeyBase64  string
+
+	// CertPath and KeyPath are PEM files of the certificate chain and private
+	// key. They're reloaded whenever they change, so that rotated certificates
+	// are picked up without a restart.
+	CertPath string
+	KeyPath  string
+
+	// KeyPassword decrypts an encrypted private key.
+	KeyPassword string
+}
+
+// Configure makes the TLS config present the certificate.
+func (c *ClientCertificate) Configure(tlsConfig *tls.Config) error {
+	fromBase64 := c.CertBase64 != "" || c.KeyBase64 != ""
+	fromPaths := c.CertPath != "" || c.KeyPath != ""
+
+	switch {
+	case fromBase64 && fromPaths:
+		return errors.New("the client certificate must be supplied either as base64 or as files, not both")
+	case fromBase64:
+		if c.CertBase64 == "" || c.KeyBase64 == "" {
+			return errors.New("both the client certificate and key must be supplied")
+		}
+
+		certificate, err := decodeClientCertificate(c.CertBase64, c.KeyBase64, c.KeyPassword)
+		if err != nil {
+			return err
+		}
+		tlsConfig.Certificates = []tls.Certificate{*certificate}
+	case fromPaths:
+		if c.CertPath == "" || c.KeyPath == "" {
+			return errors.New("both the client certificate and key must be supplied")
+		}
+
+		certificate, err := newReloadingCertificate(c.CertPath, c.KeyPath, c.KeyPassword)
+		if err != nil {
+			return err
+		}
+		tlsConfig.GetClientCertificate = certificate.get
+	}
+
+	return nil
+}
+
+// reloadingCertificate serves a client certificate from files, reloading it
+// whenever either of them changes.
+type reloadingCertificate struct {
+	certPath string
+	keyPath  string
+	password string
+
+	mutex       sync.Mutex
+	certificate *tls.Certificate
+	certModTime time.Time
+	keyModTime  time.Time
+}
+
+func newReloadingCertificate(certPath, keyPath, password string) (*reloadingCertificate, error) {
+	out := &reloadingCertificate{certPath: certPath, keyPath: keyPath, password: password}
+
+	if _, err := out.get(nil); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// get returns the current certificate. If the files changed but can't be
+// loaded, for example because they're mid-rotation, the previous certificate
+// is returned.
+func (c *reloadingCertificate) get(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	certInfo, certErr := os.Stat(c.certPath)
+	keyInfo, keyErr := os.Stat(c.keyPath)
+
+	if certErr == nil && keyErr == nil && c.certificate != nil &&
+		certInfo.ModTime().Equal(c.certModTime) && keyInfo.ModTime().Equal(c.keyModTime) {
+		return c.certificate, nil
+	}
+
+	certificate, err := c.load()
+	if err != nil {
+		if c.certificate != nil {
+			return c.certificate, nil
+		}
+		return nil, err
+	}
+
+	c.certificate = certificate
+	if certErr == nil && keyErr == nil {
+		c.certModTime, c.keyModTime = certInfo.ModTime(), keyInfo.ModTime()
+	}
+
+	return c.certificate, nil
+}
+
+func (c *reloadingCertificate) load() (*tls.Certificate, error) {
+	certPEM, err := os.ReadFile(c.certPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't read client certificate file")
+	}
+
+	keyPEM, err := os.ReadFile(c.keyPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't read client key file")
+	}
+
+	return parseClientCertificate(certPEM, keyPEM, c.password)
+}
+
+func decodeClientCertificate(certBase64, keyBase64, password string) (*tls.Certificate, error) {
+	certPEM, err := base64.StdEncoding.DecodeString(certBase64)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid base64 client certificate")
+	}
+
+	keyPEM, err := base64.StdEncoding.DecodeString(keyBase64)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid base64 client key")
+	}
+
+	return parseClientCertificate(certPEM, keyPEM, password)
+}
+
+// parseClientCertificate parses a PEM certificate chain and private key.
+// Encrypted PKCS #8 keys, as produced by OpenSSL 3 and most current tooling,
+// and keys encrypted with the legacy PEM encryption are decrypted using the
+// password.
+func parseClientCertificate(certPEM, keyPEM []byte, password string) (*tls.Certificate, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, errors.New("client key contains no PEM data")
+	}
+
+	//nolint:staticcheck // legacy PEM encryption is insecure, but still what many tools produce
+	if x509.IsEncryptedPEMBlock(block) {
+		if password == "" {
+			return nil, errors.New("client key is encrypted, but no password was supplied")
+		}
+
+		//nolint:staticcheck // see above
+		der, err := x509.DecryptPEMBlock(block, []byte(password))
+		if err != nil {
+			return nil, errors.Wrap(err, "couldn't decrypt client key")
+		}
+
+		keyPEM = pem.EncodeToMemory(&pem.Block{Type: block.Type, Bytes: der})
+	} else if block.Type == "ENCRYPTED PRIVATE KEY" {
+		if password == "" {
+			return nil, errors.New("client key is encrypted, but no password was supplied")
+		}
+
+		key, err := pkcs8.ParsePKCS8PrivateKey(block.Bytes, []byte(password))
+		if err != nil {
+			return nil, errors.Wrap(err, "couldn't decrypt client key")
+		}
+
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			return nil, errors.Wrap(err, "couldn't decrypt client key")
+		}
+
+		keyPEM = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	}
+
+	certificate, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid client certificate")
+	}
+
+	return &certificate, nil
+}
@@ -0,0 +1,205 @@
+package transport
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newTestClientCertificate returns a self-signed PEM certificate and key for
+// the common name. The key is encrypted if a password is given.
+func newTestClientCertificate(t *testing.T, commonName, password string) (certPEM, keyPEM []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err, "could not generate key")
+
+	der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: commonName}}, &key.PublicKey, key)
+	require.NoError(t, err, "could not create certificate")
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err, "could not marshal key")
+
+	keyBlock := &pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}
+	if password != "" {
+		//nolint:staticcheck // legacy PEM encryption is what's being tested
+		keyBlock, err = x509.EncryptPEMBlock(rand.Reader, keyBlock.Type, keyDER, []byte(password), x509.PEMCipherAES256)
+		require.NoError(t, err, "could not encrypt key")
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(keyBlock)
+}
+
+func TestNewClientCertificate(t *testing.T) {
+	var commonNames []string
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		commonNames = append(commonNames, r.TLS.PeerCertificates[0].Subject.CommonName)
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+
+	get := func(t *testing.T, config *Config) {
+		config.CACertBase64 = base64.StdEncoding.EncodeToString(caPEM)
+
+		transport, err := New(config)
+		require.NoError(t, err, "could not create transport")
+		t.Cleanup(transport.CloseIdleConnections)
+
+		res, err := (&http.Client{Transport: transport}).Get(server.URL)
+		require.NoError(t, err, "could not perform request")
+		_ = res.Body.Close()
+	}
+
+	t.Run("with base64", func(t *testing.T) {
+		certPEM, keyPEM := newTestClientCertificate(t, "base64", "")
+
+		get(t, &Config{
+			ClientCertificate: ClientCertificate{
+				CertBase64: base64.StdEncoding.EncodeToString(certPEM),
+				KeyBase64:  base64.StdEncoding.EncodeToString(keyPEM),
+			},
+		})
+
+		require.Equal(t, "base64", commonNames[len(commonNames)-1])
+	})
+
+	t.Run("with an encrypted key", func(t *testing.T) {
+		certPEM, keyPEM := newTestClientCertificate(t, "encrypted", "secret")
+
+		get(t, &Config{
+			ClientCertificate: ClientCertificate{
+				CertBase64:  base64.StdEncoding.EncodeToString(certPEM),
+				KeyBase64:   base64.StdEncoding.EncodeToString(keyPEM),
+				KeyPassword: "secret",
+			},
+		})
+
+		require.Equal(t, "encrypted", commonNames[len(commonNames)-1])
+	})
+
+	t.Run("with files", func(t *testing.T) {
+		dir := t.TempDir()
+		certPath, keyPath := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+
+		certPEM, keyPEM := newTestClientCertificate(t, "files", "")
+		require.NoError(t, os.WriteFile(certPath, certPEM, 0o600))
+		require.NoError(t, os.WriteFile(keyPath, keyPEM, 0o600))
+
+		get(t, &Config{ClientCertificate: ClientCertificate{CertPath: certPath, KeyPath: keyPath}})
+
+		require.Equal(t, "files", commonNames[len(commonNames)-1])
+	})
+}
+
+func TestReloadingCertificate(t *testing.T) {
+	dir := t.TempDir()
+	certPath, keyPath := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+
+	write := func(certPEM, keyPEM []byte, modTime time.Time) {
+		require.NoError(t, os.WriteFile(certPath, certPEM, 0o600))
+		require.NoError(t, os.WriteFile(keyPath, keyPEM, 0o600))
+		require.NoError(t, os.Chtimes(certPath, modTime, modTime))
+		require.NoError(t, os.Chtimes(keyPath, modTime, modTime))
+	}
+
+	certPEM, keyPEM := newTestClientCertificate(t, "original", "")
+	write(certPEM, keyPEM, time.Now().Add(-time.Hour))
+
+	sut, err := newReloadingCertificate(certPath, keyPath, "")
+	require.NoError(t, err, "could not load certificate")
+
+	commonName := func() string {
+		certificate, err := sut.get(nil)
+		require.NoError(t, err, "could not get certificate")
+
+		leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+		require.NoError(t, err, "could not parse certificate")
+
+		return leaf.Subject.CommonName
+	}
+
+	require.Equal(t, "original", commonName())
+
+	t.Run("when the files are mid-rotation", func(t *testing.T) {
+		rotatedCertPEM, _ := newTestClientCertificate(t, "rotated", "")
+		write(rotatedCertPEM, keyPEM, time.Now().Add(-time.Minute))
+
+		require.Equal(t, "original", commonName(), "the previous certificate should be kept")
+	})
+
+	t.Run("once rotated", func(t *testing.T) {
+		rotatedCertPEM, rotatedKeyPEM := newTestClientCertificate(t, "rotated", "")
+		write(rotatedCertPEM, rotatedKeyPEM, time.Now())
+
+		require.Equal(t, "rotated", commonName())
+	})
+}
+
+func TestParseClientCertificate(t *testing.T) {
+	certPEM, keyPEM := newTestClientCertificate(t, "encrypted", "secret")
+
+	t.Run("without a password", func(t *testing.T) {
+		_, err := parseClientCertificate(certPEM, keyPEM, "")
+
+		require.EqualError(t, err, "client key is encrypted, but no password was supplied")
+	})
+
+	t.Run("with an encrypted PKCS #8 key", func(t *testing.T) {
+		// Generated with OpenSSL 3 using "openssl genpkey -aes256", which
+		// encrypts the key with PBES2.
+		certPEM, err := os.ReadFile("fixtures/pkcs8_encrypted.crt")
+		require.NoError(t, err, "could not read certificate")
+
+		keyPEM, err := os.ReadFile("fixtures/pkcs8_encrypted.key")
+		require.NoError(t, err, "could not read key")
+
+		certificate, err := parseClientCertificate(certPEM, keyPEM, "secret")
+		require.NoError(t, err)
+
+		leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+		require.NoError(t, err, "could not parse certificate")
+		require.Equal(t, "pkcs8", leaf.Subject.CommonName)
+
+		_, err = parseClientCertificate(certPEM, keyPEM, "")
+		require.EqualError(t, err, "client key is encrypted, but no password was supplied")
+
+		_, err = parseClientCertificate(certPEM, keyPEM, "wrong")
+		require.ErrorContains(t, err, "couldn't decrypt client key")
+	})
+
+	t.Run("with a wrong password", func(t *testing.T) {
+		// Legacy PEM encryption can't always detect a wrong password, in which
+		// case the decrypted key doesn't match the certificate.
+		_, err := parseClientCertificate(certPEM, keyPEM, "wrong")
+
+		require.Error(t, err)
+	})
+}
+
+func TestClientCertificateConfigure(t *testing.T) {
+	err := (&ClientCertificate{CertPath: "tls.crt"}).Configure(new(tls.Config))
+
+	require.EqualError(t, err, "both the client certificate and key must be supplied")
+}
@@ -0,0 +1,178 @@
+package transport
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// EgressDeniedError is returned when a host resolves only to addresses outside
+// the allowed egress ranges.
+type EgressDeniedError struct {
+	Host      string
+	Addresses []netip.Addr
+}
+
+func (e *EgressDeniedError) Error() string {
+	addresses := make([]string, len(e.Addresses))
+	for i, addr := range e.Addresses {
+		addresses[i] = addr.String()
+	}
+
+	return fmt.Sprintf("%s resolves to %s, outside the allowed egress ranges", e.Host, strings.Join(addresses, ", "))
+}
+
+// parseEgressRanges parses CIDR ranges, treating bare IP addresses as single
+// address ranges.
+func parseEgressRanges(raw []string) ([]netip.Prefix, error) {
+	out := make([]netip.Prefix, 0, len(raw))
+
+	for _, value := range raw {
+		value = strings.TrimSpace(value)
+
+		if !strings.Contains(value, "/") {
+			addr, err := netip.ParseAddr(value)
+			if err != nil {
+				return nil, errors.Wrapf(err, "invalid egress range %q", value)
+			}
+			out = append(out, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid egress range %q", value)
+		}
+		out = append(out, prefix.Masked())
+	}
+
+	return out, nil
+}
+
+type pinnedResolutionKey struct{}
+
+// pinnedResolution holds the addresses the hosts of a request resolved to.
+type pinnedResolution struct {
+	mutex     sync.Mutex
+	addresses map[string][]netip.Addr
+}
+
+// WithPinnedResolution returns a context pinning the addresses hosts resolve
+// to for as long as it's used, so that all connections made by a request,
+// including the ones following redirects, go to the addresses checked when
+// the request first connected to each host.
+func WithPinnedResolution(ctx context.Context) context.Context {
+	return context.WithValue(ctx, pinnedResolutionKey{}, &pinnedResolution{
+		addresses: make(map[string][]netip.Addr),
+	})
+}
+
+// egressDialer connects only to addresses within the allowed ranges, checking
+// them after resolution so that the hostname can't later resolve elsewhere.
+type egressDialer struct {
+	dialer  *net.Dialer
+	lookup  func(ctx context.Context, network, host string) ([]netip.Addr, error)
+	allowed []netip.Prefix
+}
+
+func newEgressDialer(allowed []netip.Prefix) *egressDialer {
+	return &egressDialer{
+		// Matches the dialer of http.DefaultTransport.
+		dialer:  &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second},
+		lookup:  net.DefaultResolver.LookupNetIP,
+		allowed: allowed,
+	}
+}
+
+func (d *egressDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+
+	addresses, err := d.addresses(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastErr error
+	for _, addr := range addresses {
+		conn, err := d.dialer.DialContext(ctx, network, net.JoinHostPort(addr.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+
+	return nil, lastErr
+}
+
+// addresses returns the allowed addresses of the host, reusing the ones pinned
+// for the request if there are any.
+func (d *egressDialer) addresses(ctx context.Context, host string) ([]netip.Addr, error) {
+	pinned, _ := ctx.Value(pinnedResolutionKey{}).(*pinnedResolution)
+
+	if pinned != nil {
+		pinned.mutex.Lock()
+		defer pinned.mutex.Unlock()
+
+		if addresses, ok := pinned.addresses[host]; ok {
+			return addresses, nil
+		}
+	}
+
+	resolved, err := d.resolve(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := make([]netip.Addr, 0, len(resolved))
+	for _, addr := range resolved {
+		if d.isAllowed(addr) {
+			allowed = append(allowed, addr)
+		}
+	}
+
+	if len(allowed) == 0 {
+		return nil, &EgressDeniedError{Host: host, Addresses: resolved}
+	}
+
+	if pinned != nil {
+		pinned.addresses[host] = allowed
+	}
+
+	return allowed, nil
+}
+
+func (d *egressDialer) resolve(ctx context.Context, host string) ([]netip.Addr, error) {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return []netip.Addr{addr.Unmap()}, nil
+	}
+
+	resolved, err := d.lookup(ctx, "ip", host)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, addr := range resolved {
+		resolved[i] = addr.Unmap()
+	}
+
+	return resolved, nil
+}
+
+func (d *egressDialer) isAllowed(addr netip.Addr) bool {
+	for _, prefix := range d.allowed {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,112 @@
+package transport
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEgressAllowedRanges(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(server.Close)
+
+	get := func(t *testing.T, ranges ...string) error {
+		transport, err := New(&Config{EgressAllowedRanges: ranges})
+		require.NoError(t, err, "could not create transport")
+
+		res, err := (&http.Client{Transport: transport}).Get(server.URL)
+		if err == nil {
+			_ = res.Body.Close()
+		}
+		return err
+	}
+
+	t.Run("within the ranges", func(t *testing.T) {
+		require.NoError(t, get(t, "10.0.0.0/8", "127.0.0.0/8"))
+	})
+
+	t.Run("with a single address", func(t *testing.T) {
+		require.NoError(t, get(t, "127.0.0.1"))
+	})
+
+	t.Run("outside the ranges", func(t *testing.T) {
+		err := get(t, "10.0.0.0/8")
+
+		var egressErr *EgressDeniedError
+		require.ErrorAs(t, err, &egressErr)
+		require.Equal(t, "127.0.0.1", egressErr.Host)
+	})
+
+	t.Run("with an invalid range", func(t *testing.T) {
+		_, err := New(&Config{EgressAllowedRanges: []string{"10.0.0.0/33"}})
+
+		require.ErrorContains(t, err, `invalid egress range "10.0.0.0/33"`)
+	})
+}
+
+func TestEgressDialer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(server.Close)
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	address := net.JoinHostPort("vcs.example.com", serverURL.Port())
+
+	// newDialer returns a dialer resolving the host to each of the given
+	// addresses in turn.
+	newDialer := func(t *testing.T, resolved ...string) (*egressDialer, *int) {
+		allowed, err := parseEgressRanges([]string{"127.0.0.0/8", "192.0.2.0/24"})
+		require.NoError(t, err)
+
+		lookups := 0
+		dialer := newEgressDialer(allowed)
+		dialer.lookup = func(context.Context, string, string) ([]netip.Addr, error) {
+			addr := netip.MustParseAddr(resolved[lookups%len(resolved)])
+			lookups++
+			return []netip.Addr{addr}, nil
+		}
+
+		return dialer, &lookups
+	}
+
+	t.Run("when the host rebinds outside the ranges", func(t *testing.T) {
+		dialer, _ := newDialer(t, "127.0.0.1", "10.0.0.1")
+
+		conn, err := dialer.DialContext(context.Background(), "tcp", address)
+		require.NoError(t, err)
+		_ = conn.Close()
+
+		_, err = dialer.DialContext(context.Background(), "tcp", address)
+		require.EqualError(t, err, "vcs.example.com resolves to 10.0.0.1, outside the allowed egress ranges")
+	})
+
+	t.Run("with the resolution pinned", func(t *testing.T) {
+		// The second resolution would point to an unreachable address.
+		dialer, lookups := newDialer(t, "127.0.0.1", "192.0.2.1")
+		ctx := WithPinnedResolution(context.Background())
+
+		for range 2 {
+			conn, err := dialer.DialContext(ctx, "tcp", address)
+			require.NoError(t, err)
+			_ = conn.Close()
+		}
+
+		require.Equal(t, 1, *lookups)
+	})
+
+	t.Run("with an IPv4-mapped IPv6 address", func(t *testing.T) {
+		dialer, _ := newDialer(t, "::ffff:10.0.0.1")
+
+		_, err := dialer.DialContext(context.Background(), "tcp", address)
+
+		var egressErr *EgressDeniedError
+		require.ErrorAs(t, err, &egressErr)
+		require.Equal(t, []netip.Addr{netip.MustParseAddr("10.0.0.1")}, egressErr.Addresses)
+	})
+}
@@ -0,0 +1,203 @@
+// Package transport builds the HTTP transport used for requests to the VCS
+// target.
+package transport
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Config describes the transport used for requests to the VCS target. The zero
+// value yields the defaults of http.DefaultTransport.
+type Config struct {
+	// CACertBase64 is a base64 encoded PEM bundle of additional trusted CAs.
+	CACertBase64 string
+
+	// CACertPaths are PEM files of additional trusted CAs, or directories
+	// containing them.
+	CACertPaths []string
+
+	// ExcludeSystemRoots makes only the additional CAs trusted, instead of
+	// adding them to the system roots.
+	ExcludeSystemRoots bool
+
+	// ClientCertificate is presented to targets requiring mutual TLS.
+	ClientCertificate ClientCertificate
+
+	// MaxIdleConns limits idle connections across all hosts. Zero keeps the
+	// default.
+	MaxIdleConns int
+
+	// MaxIdleConnsPerHost limits idle connections to the target. Zero keeps
+	// the default.
+	MaxIdleConnsPerHost int
+
+	// TLSMinVersion is the minimum TLS version, either "1.2" or "1.3".
+	// Defaults to 1.2.
+	TLSMinVersion string
+
+	// DisableHTTP2 makes the transport only speak HTTP/1.1.
+	DisableHTTP2 bool
+
+	// EgressAllowedRanges are the CIDR ranges the transport may connect to.
+	// Hostnames are resolved by the transport and connections to addresses
+	// outside of them fail with an EgressDeniedError. With a proxy
+	// configured, the ranges apply to the proxy instead. Empty allows all
+	// addresses.
+	EgressAllowedRanges []string
+}
+
+// HasCustomCAs returns whether any additional CAs are configured.
+func (c *Config) HasCustomCAs() bool {
+	return c.CACertBase64 != "" || len(c.CACertPaths) > 0
+}
+
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// New builds a transport from the defaults of http.DefaultTransport, keeping
+// its proxy settings, dial timeouts and connection pooling.
+func New(config *Config) (*http.Transport, error) {
+	out := http.DefaultTransport.(*http.Transport).Clone()
+
+	minVersion := uint16(tls.VersionTLS12)
+	if config.TLSMinVersion != "" {
+		var ok bool
+		if minVersion, ok = tlsVersions[config.TLSMinVersion]; !ok {
+			return nil, errors.Errorf("unsupported TLS version %q, use 1.2 or 1.3", config.TLSMinVersion)
+		}
+	}
+
+	out.TLSClientConfig = &tls.Config{MinVersion: minVersion}
+
+	if config.HasCustomCAs() {
+		roots, err := rootCAs(config)
+		if err != nil {
+			return nil, err
+		}
+		out.TLSClientConfig.RootCAs = roots
+	}
+
+	if err := config.ClientCertificate.Configure(out.TLSClientConfig); err != nil {
+		return nil, err
+	}
+
+	if config.MaxIdleConns > 0 {
+		out.MaxIdleConns = config.MaxIdleConns
+	}
+
+	if config.MaxIdleConnsPerHost > 0 {
+		out.MaxIdleConnsPerHost = config.MaxIdleConnsPerHost
+	}
+
+	if len(config.EgressAllowedRanges) > 0 {
+		allowed, err := parseEgressRanges(config.EgressAllowedRanges)
+		if err != nil {
+			return nil, err
+		}
+		out.DialContext = newEgressDialer(allowed).DialContext
+	}
+
+	if config.DisableHTTP2 {
+		// A non-nil empty map disables the automatic HTTP/2 upgrade.
+		out.ForceAttemptHTTP2 = false
+		out.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+	}
+
+	return out, nil
+}
+
+func rootCAs(config *Config) (*x509.CertPool, error) {
+	roots := x509.NewCertPool()
+
+	if !config.ExcludeSystemRoots {
+		systemRoots, err := x509.SystemCertPool()
+		if err != nil {
+			return nil, errors.Wrap(err, "couldn't load system CA certificates")
+		}
+		roots = systemRoots
+	}
+
+	if config.CACertBase64 != "" {
+		pem, err := base64.StdEncoding.DecodeString(config.CACertBase64)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid base64 CA certificate")
+		}
+
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, errors.New("base64 CA certificate contains no valid PEM certificates")
+		}
+	}
+
+	for _, path := range config.CACertPaths {
+		if err := appendCertsFromPath(roots, path); err != nil {
+			return nil, err
+		}
+	}
+
+	return roots, nil
+}
+
+// appendCertsFromPath loads the certificates from a PEM file, or from all
+// .pem, .crt and .cer files in a directory.
+func appendCertsFromPath(roots *x509.CertPool, path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return errors.Wrap(err, "couldn't read CA certificate path")
+	}
+
+	if !info.IsDir() {
+		return appendCertsFromFile(roots, path)
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return errors.Wrapf(err, "couldn't read CA certificate directory %s", path)
+	}
+
+	var loaded int
+	for _, entry := range entries {
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".pem", ".crt", ".cer":
+		default:
+			continue
+		}
+
+		if entry.IsDir() {
+			continue
+		}
+
+		if err := appendCertsFromFile(roots, filepath.Join(path, entry.Name())); err != nil {
+			return err
+		}
+		loaded++
+	}
+
+	if loaded == 0 {
+		return errors.Errorf("CA certificate directory %s contains no .pem, .crt or .cer files", path)
+	}
+
+	return nil
+}
+
+func appendCertsFromFile(roots *x509.CertPool, path string) error {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return errors.Wrap(err, "couldn't read CA certificate file")
+	}
+
+	if !roots.AppendCertsFromPEM(pem) {
+		return errors.Errorf("CA certificate file %s contains no valid PEM certificates", path)
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package transport
+
+import (
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(server.Close)
+
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+
+	get := func(t *testing.T, config *Config) error {
+		transport, err := New(config)
+		require.NoError(t, err, "could not create transport")
+
+		res, err := (&http.Client{Transport: transport}).Get(server.URL)
+		if err == nil {
+			_ = res.Body.Close()
+		}
+		return err
+	}
+
+	t.Run("without custom CAs", func(t *testing.T) {
+		require.Error(t, get(t, &Config{}))
+	})
+
+	t.Run("with a base64 CA", func(t *testing.T) {
+		require.NoError(t, get(t, &Config{CACertBase64: base64.StdEncoding.EncodeToString(caPEM)}))
+	})
+
+	t.Run("with a CA file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "ca.pem")
+		require.NoError(t, os.WriteFile(path, caPEM, 0o600))
+
+		require.NoError(t, get(t, &Config{CACertPaths: []string{path}, ExcludeSystemRoots: true}))
+	})
+
+	t.Run("with a CA directory", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "ca.crt"), caPEM, 0o600))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "README"), []byte("not a certificate"), 0o600))
+
+		require.NoError(t, get(t, &Config{CACertPaths: []string{dir}}))
+	})
+
+	t.Run("with an invalid CA", func(t *testing.T) {
+		_, err := New(&Config{CACertBase64: base64.StdEncoding.EncodeToString([]byte("garbage"))})
+
+		require.EqualError(t, err, "base64 CA certificate contains no valid PEM certificates")
+	})
+
+	t.Run("with an empty CA directory", func(t *testing.T) {
+		dir := t.TempDir()
+
+		_, err := New(&Config{CACertPaths: []string{dir}})
+
+		require.EqualError(t, err, "CA certificate directory "+dir+" contains no .pem, .crt or .cer files")
+	})
+}
+
+func TestNewSettings(t *testing.T) {
+	t.Run("with defaults", func(t *testing.T) {
+		sut, err := New(&Config{})
+		require.NoError(t, err, "could not create transport")
+
+		require.NotNil(t, sut.Proxy, "proxy settings should be kept")
+		require.True(t, sut.ForceAttemptHTTP2)
+		require.EqualValues(t, tls.VersionTLS12, sut.TLSClientConfig.MinVersion)
+		require.Nil(t, sut.TLSClientConfig.RootCAs, "system roots should be used")
+	})
+
+	t.Run("with overrides", func(t *testing.T) {
+		sut, err := New(&Config{
+			MaxIdleConns:        10,
+			MaxIdleConnsPerHost: 5,
+			TLSMinVersion:       "1.3",
+			DisableHTTP2:        true,
+		})
+		require.NoError(t, err, "could not create transport")
+
+		require.Equal(t, 10, sut.MaxIdleConns)
+		require.Equal(t, 5, sut.MaxIdleConnsPerHost)
+		require.EqualValues(t, tls.VersionTLS13, sut.TLSClientConfig.MinVersion)
+		require.False(t, sut.ForceAttemptHTTP2)
+		require.NotNil(t, sut.TLSNextProto)
+		require.Empty(t, sut.TLSNextProto)
+	})
+
+	t.Run("with an unsupported TLS version", func(t *testing.T) {
+		_, err := New(&Config{TLSMinVersion: "1.1"})
+
+		require.EqualError(t, err, `unsupported TLS version "1.1", use 1.2 or 1.3`)
+	})
+}